@@ -1,19 +1,41 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	cryptorand "crypto/rand"
+	"crypto/subtle"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"embed"
+	"encoding/hex"
+	"encoding/pem"
 	"errors"
 	"flag"
 	"fmt"
+	"html/template"
+	"io"
 	"io/ioutil"
+	"math"
+	"math/big"
 	"math/rand"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
+	"path"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/greglange/gocards/pkg/gocards"
@@ -27,13 +49,15 @@ import (
 
 // List of main functions, functions that are run because of a command line flag.
 var mainFuncs = map[string]func(*options) error{
-	"clean": mainClean,
-	"http":  mainHttp,
+	"clean":    mainClean,
+	"forecast": mainForecast,
+	"http":     mainHttp,
+	"passwd":   mainPasswd,
 }
 
-var boolFlags = []string{}
+var boolFlags = []string{"tls"}
 
-var stringFlags = []string{"file", "path"}
+var stringFlags = []string{"addr", "auth", "autosave", "cert", "days", "file", "key", "new-per-day", "path", "port", "profile", "reviews-per-day", "user"}
 
 type options struct {
 	b map[string]bool
@@ -68,30 +92,342 @@ func getOptions() *options {
 }
 
 // Struct to hold information about a session of doing cards.
+// A session can do cards from more than one card set.
+// When typeAnswer is true, answers are typed in and compared to the back of each card.
+// When tag is set, only cards with that tag are done.
+// A session keeps doing cards in the profile it was started in.
+// When logging in is turned on, only the user that started a session can use it.
 type cardSetSession struct {
-	cardSet          *gocards.CardSet
+	cardSets         []*gocards.CardSet
 	spacedRepetition bool
 	cardType         string
 	cardInterval     int
-	cardsDone        map[string]bool
+	cardsDone        map[*gocards.Card]bool
+	typeAnswer       bool
+	tag              string
+	id               string
+	lastUsed         time.Time
+	profile          *profile
+	user             string
+}
+
+// sessionTimeout is how long a session is kept after it was last used.
+const sessionTimeout = 2 * time.Hour
+
+// description returns the type of cards done in the session for display.
+func (s *cardSetSession) description() string {
+	description := ""
+	if s.cardType == "all" {
+		description = "all"
+	} else if s.cardType == "due_new" {
+		description = "due or new"
+	} else if s.cardType == "" {
+		description = fmt.Sprintf("interval %d day(s)", s.cardInterval)
+	} else {
+		description = s.cardType
+	}
+	if s.tag != "" {
+		description = "#" + s.tag + " " + description
+	}
+	return description
 }
 
 // Struct with data needed to serve web pages and respond to requests.
 // This struct is passed to the http.Handle function.
+// Sessions are kept by id so more than one browser tab or device can do cards at the same time.
+// The mutex is held while a request reads or changes sessions, card sets and profiles because they are shared by all requests.
+// It is not held while getting images from other web sites or writing pages to the browser, so a slow page does not hold up other requests.
+// Profiles are loaded the first time they are used.
+// When auth is set, users log in with a password from the passwords file.
+// The autosave field is -1 when data is only saved with the "save" button, 0 to save after each request and otherwise the seconds between saves.
 type httpHandler struct {
-	o        *options
+	o         *options
+	profiles  map[string]*profile
+	sessions  map[string]*cardSetSession
+	mutex     sync.Mutex
+	templates map[string]*template.Template
+	auth      string
+	passwords gocards.Passwords
+	authKey   []byte
+	autosave  int
+}
+
+// requestState holds what is only for the request being served.
+// It is passed to the functions that serve a request instead of being kept in the httpHandler shared by all requests.
+// The user and csrf token are set when logging in is turned on.
+// The profile is the profile the request uses.
+// The page and its template name are set by render and the page is written after the mutex is unlocked.
+type requestState struct {
+	user     string
+	csrf     string
+	profile  *profile
+	template string
+	page     page
+}
+
+// pageAuth is in the data of every web page.
+// The CSRF token is posted by every form on the page.
+// The user can log out when User is set.
+type pageAuth struct {
+	User string
+	CSRF string
+}
+
+func (a *pageAuth) setAuth(user, csrf string) {
+	a.User, a.CSRF = user, csrf
+}
+
+// prepare does nothing for pages that have nothing slow to make.
+func (a *pageAuth) prepare() {
+}
+
+// page is the data for a web page.
+// prepare makes the parts of the page that are slow to make and is called without the mutex held.
+type page interface {
+	setAuth(user, csrf string)
+	prepare()
+}
+
+// profile holds the card sets of a profile and the daily quota shared by them.
+// Card files are shared by all profiles but each profile has its own data files.
+// The save map has the ids of card sets with data that needs to be written to disk.
+type profile struct {
+	name     string
+	path     string
 	cardSets []*gocards.CardSet
-	session  *cardSetSession
+	quota    *gocards.DailyQuota
 	save     map[string]bool
 }
 
+// profileCookie is the name of the cookie with the name of the profile being used.
+const profileCookie = "gocards_profile"
+
+// authTimeout is how long a user stays logged in.
+const authTimeout = 7 * 24 * time.Hour
+
+// authCookie is the name of the cookie with the signed name of the user logged in.
+const authCookie = "gocards_auth"
+
+// loginCookie is the name of the cookie with a random value that the CSRF token of the login form is made from.
+const loginCookie = "gocards_login"
+
 // newHttpHandler returns a populated *httpHandler struct.
+// Loads the profile from the "profile" option, which is used by browsers that have not chosen a profile.
+// Loads the templates for the web pages.
+// Loads the "passwords" and "authKey" files when the "auth" option is set.
+// Parses the "autosave" option.
+// An error is returned if one occurs.
+func newHttpHandler(o *options) (*httpHandler, error) {
+	h := &httpHandler{o, map[string]*profile{}, map[string]*cardSetSession{}, sync.Mutex{}, nil, o.s["auth"], nil, nil, -1}
+	var err error
+	if o.s["autosave"] != "" {
+		h.autosave, err = strconv.Atoi(o.s["autosave"])
+		if err != nil || h.autosave < 0 {
+			return nil, errors.New("--autosave must be a number that is not negative")
+		}
+	}
+	_, err = h.getProfile(o.s["profile"])
+	if err != nil {
+		return nil, err
+	}
+	if h.auth != "" {
+		if h.auth != "basic" && h.auth != "form" {
+			return nil, errors.New("--auth must be basic or form")
+		}
+		h.passwords, err = gocards.LoadPasswords(filepath.Join(o.s["path"], "passwords"))
+		if err != nil {
+			return nil, err
+		}
+		if len(h.passwords) == 0 {
+			return nil, errors.New("No users found in passwords file, add a user with --passwd --user <name>")
+		}
+		h.authKey, err = gocards.LoadAuthKey(filepath.Join(o.s["path"], "authKey"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	h.templates, err = loadTemplates(o.s["path"])
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// getProfile returns the profile with the name passed in.
+// The profile is loaded if it has not been used yet.
+// Loads the card sets with the data files of the profile.
+// Loads the "dailyCounts" file of the profile if it exists and shares the daily quota with all card sets.
+// An error is returned if one occurs.
+func (h *httpHandler) getProfile(name string) (*profile, error) {
+	if p, ok := h.profiles[name]; ok {
+		return p, nil
+	}
+	cardSets, err := loadCardSets(h.o, name)
+	if err != nil {
+		return nil, err
+	}
+	profilePath := gocards.ProfilePath(h.o.s["path"], name)
+	quota, err := newDailyQuota(h.o, profilePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, cardSet := range cardSets {
+		cardSet.Quota = quota
+	}
+	p := &profile{name, profilePath, cardSets, quota, map[string]bool{}}
+	h.profiles[name] = p
+	return p, nil
+}
+
+// profileNames returns the names of the profiles in the root path and the profiles that have been used.
+// The default profile is first.
+func (h *httpHandler) profileNames() ([]string, error) {
+	names, err := gocards.Profiles(h.o.s["path"])
+	if err != nil {
+		return nil, err
+	}
+	for name := range h.profiles {
+		if name != "" && !inSlice(names, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{""}, names...), nil
+}
+
+// requestProfile returns the name of the profile used by a request.
+// When logging in is turned on, each user uses the profile with their user name.
+// Otherwise this is the profile chosen by the browser of the request.
+// Only profiles with a directory in the root path can be chosen, so a cookie can't load any profile it names.
+// Returns the profile from the "profile" option if no profile has been chosen.
+func (h *httpHandler) requestProfile(r *http.Request, rs *requestState) string {
+	if h.auth != "" {
+		return rs.user
+	}
+	cookie, err := r.Cookie(profileCookie)
+	if err != nil || !gocards.ValidProfile(cookie.Value) {
+		return h.o.s["profile"]
+	}
+	if cookie.Value != "" && cookie.Value != h.o.s["profile"] {
+		f, err := os.Stat(gocards.ProfilePath(h.o.s["path"], cookie.Value))
+		if err != nil || !f.IsDir() {
+			return h.o.s["profile"]
+		}
+	}
+	return cookie.Value
+}
+
+// switchProfile changes the profile used by the browser of a request to the one in the form posted.
+// The directory of a profile that does not exist yet is made.
+// Profiles can't be changed when logging in is turned on.
+func (h *httpHandler) switchProfile(w http.ResponseWriter, r *http.Request, rs *requestState) {
+	if h.auth != "" {
+		h.pageMessage(w, rs, "Each user has their own profile when logging in is turned on")
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("profile"))
+	if !gocards.ValidProfile(name) {
+		h.pageMessage(w, rs, "Invalid profile name")
+		return
+	}
+	err := os.MkdirAll(gocards.ProfilePath(h.o.s["path"], name), 0755)
+	if err != nil {
+		h.pageError(w, rs, err)
+		return
+	}
+	_, err = h.getProfile(name)
+	if err != nil {
+		h.pageError(w, rs, err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     profileCookie,
+		Value:    name,
+		Path:     "/",
+		MaxAge:   365 * 24 * 60 * 60,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+// pageTemplates are the names of the templates for the web pages.
+// Each page template is parsed with "layout.html", which has the parts of pages shared by all pages.
+var pageTemplates = []string{"main", "front", "back", "quiz", "quizResult", "message", "leeches", "stats", "login"}
+
+// loadTemplates parses the templates for the web pages.
+// A template in the "templates" directory in the root path is used instead of the built in template with the same name.
+// Returns a map of page names to templates.
+// An error is returned if one occurs.
+func loadTemplates(rootPath string) (map[string]*template.Template, error) {
+	templates := map[string]*template.Template{}
+	for _, name := range pageTemplates {
+		t := template.New(name)
+		for _, file := range []string{"layout.html", name + ".html"} {
+			text, err := readTemplate(rootPath, file)
+			if err != nil {
+				return nil, err
+			}
+			_, err = t.Parse(string(text))
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("Unable to parse template %s: %s", file, err))
+			}
+		}
+		templates[name] = t
+	}
+	return templates, nil
+}
+
+// readTemplate returns a template file from the "templates" directory in the root path if it exists.
+// Otherwise the built in template file is returned.
+func readTemplate(rootPath, file string) ([]byte, error) {
+	override := filepath.Join(rootPath, "templates", file)
+	if _, err := os.Stat(override); err == nil {
+		return os.ReadFile(override)
+	}
+	return templateFiles.ReadFile("templates/" + file)
+}
+
+// render sets the web page displayed for a request using its template and the data passed in.
+// The page is written by writePage after the mutex is unlocked.
+// The CSRF token of the request is added to the data.
+// The user is added when logging in with the login form, so the page can show a button to log out.
+func (h *httpHandler) render(rs *requestState, name string, data page) {
+	user := ""
+	if h.auth == "form" {
+		user = rs.user
+	}
+	data.setAuth(user, rs.csrf)
+	rs.template, rs.page = name, data
+}
+
+// writePage writes the web page set by render for a request.
+// The slow parts of the page, like images from other web sites, are made without the mutex held.
+// The mutex is held while the template is executed because pages can have card data that other requests change.
+// The page is written to the browser after the mutex is unlocked.
+func (h *httpHandler) writePage(w http.ResponseWriter, rs *requestState) {
+	rs.page.prepare()
+	var buf bytes.Buffer
+	h.mutex.Lock()
+	err := h.templates[rs.template].ExecuteTemplate(&buf, "layout", rs.page)
+	h.mutex.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(buf.Bytes())
+}
+
+// loadCardSets returns the card sets found from the path option.
 // Loads the "cardFiles" file if it exists.
 // Finds card set files.
+// Uses the data files of the profile passed in.
 // Loads card files and data files.
-// Sorts the cardSets slice by card set id.
+// Sorts the card sets by card set id.
 // An error is returned if one occurs.
-func newHttpHandler(o *options) (*httpHandler, error) {
+func loadCardSets(o *options, profile string) ([]*gocards.CardSet, error) {
 	cardFilesPath := filepath.Join(o.s["path"], "cardFiles")
 	paths, err := gocards.LoadCardSetPaths(cardFilesPath)
 	if err != nil {
@@ -101,6 +437,10 @@ func newHttpHandler(o *options) (*httpHandler, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = gocards.UseProfile(o.s["path"], profile, cardSets)
+	if err != nil {
+		return nil, err
+	}
 	err = gocards.LoadCardSets(cardSets)
 	if err != nil {
 		return nil, err
@@ -109,12 +449,39 @@ func newHttpHandler(o *options) (*httpHandler, error) {
 		return cardSets[i].Id < cardSets[j].Id
 	}
 	sort.Slice(cardSets, s)
-	return &httpHandler{o, cardSets, nil, map[string]bool{}}, nil
+	return cardSets, nil
+}
+
+// newDailyQuota returns a *gocards.DailyQuota with the limits for all card sets from the command line.
+// The counts of cards done today are loaded from the "dailyCounts" file in the directory passed in if it exists.
+// An error is returned if one occurs.
+func newDailyQuota(o *options, dir string) (*gocards.DailyQuota, error) {
+	limits := []int{0, 0}
+	for i, f := range []string{"new-per-day", "reviews-per-day"} {
+		if o.s[f] == "" {
+			continue
+		}
+		limit, err := strconv.Atoi(o.s[f])
+		if err != nil || limit < 0 {
+			return nil, errors.New(fmt.Sprintf("--%s must be a number that is not negative", f))
+		}
+		limits[i] = limit
+	}
+	quota := gocards.NewDailyQuota(limits[0], limits[1])
+	err := quota.Load(filepath.Join(dir, "dailyCounts"), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return quota, nil
 }
 
 // ServeHttp serves web pages.
 // Parses the path of requests and calls the right function based on that path.
 // When a "save" form post is received, any card sets with data that need to be saved are written to disk.
+// When the "auth" option is set, requests from users that are not logged in are not served.
+// Requests use the profile chosen by the browser and a "profile" form post changes the profile.
+// When autosave is 0, data that needs to be saved is written to disk after each request.
+// The mutex is only held while serveLocked runs and while the template of the page is executed.
 func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// this is supposed to prevent the browser from caching pages
 	// https://stackoverflow.com/questions/69597242/golang-prevent-browser-cache-pages-when-clicking-back-button
@@ -123,52 +490,246 @@ func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Pragma", "no-cache")
 	w.Header().Set("X-Accel-Expires", "0")
 
+	rs := &requestState{}
+	if h.authenticate(w, r, rs) {
+		h.serveLocked(w, r, rs)
+	}
+	if rs.page != nil {
+		h.writePage(w, rs)
+	}
+}
+
+// serveLocked serves a request that has been authenticated with the mutex held.
+// When autosave is 0, data that needs to be saved is written to disk before the mutex is unlocked.
+func (h *httpHandler) serveLocked(w http.ResponseWriter, r *http.Request, rs *requestState) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.autosave == 0 {
+		// deferred calls run in reverse order so this runs before the mutex is unlocked
+		defer h.autosaveProfiles()
+	}
+
+	var err error
+	rs.profile, err = h.getProfile(h.requestProfile(r, rs))
+	if err != nil {
+		h.pageError(w, rs, err)
+		return
+	}
+
 	if r.URL.Path == "/" {
 		r.ParseForm()
 		if r.Method == "POST" {
 			action := r.FormValue("action")
 			if action == "" {
-				pageMessage(w, "Action not defined")
+				h.pageMessage(w, rs, "Action not defined")
 			} else if action == "save" {
-				err := h.saveCardSets()
+				err := rs.profile.saveCardSets()
 				if err != nil {
-					pageMessage(w, "Unable to save card sets")
+					h.pageMessage(w, rs, "Unable to save card sets")
 					return
 				}
-				h.pageMain(w, r)
+				h.pageMain(w, r, rs)
 			} else if action == "main" {
-				h.pageMain(w, r)
+				h.pageMain(w, r, rs)
 			} else {
-				pageMessage(w, "Invalid action")
+				h.pageMessage(w, rs, "Invalid action")
 			}
 		} else {
-			h.pageMain(w, r)
+			h.pageMain(w, r, rs)
 		}
+	} else if r.URL.Path == "/profile" && r.Method == "POST" {
+		r.ParseForm()
+		h.switchProfile(w, r, rs)
+	} else if r.URL.Path == "/stats" {
+		h.pageStats(w, r, rs)
+	} else if r.URL.Path == "/leeches" {
+		h.pageLeeches(w, r, rs)
 	} else {
-		h.cardSet(w, r)
+		h.cardSet(w, r, rs)
+	}
+}
+
+// authenticate checks that the user of a request is logged in.
+// Returns true if the request should be served.
+// Otherwise a response has been written and false is returned.
+// A user logged in has a signed cookie with their name in it.
+// With "basic" auth, the cookie is set after the user name and password from the browser are checked.
+// With "form" auth, the cookie is set after the user logs in with the form on the "/login" page.
+// The "/logout" path removes the cookie.
+// POSTs must have the CSRF token for the cookie of the user.
+// The user and CSRF token are set in the request state passed in.
+func (h *httpHandler) authenticate(w http.ResponseWriter, r *http.Request, rs *requestState) bool {
+	if h.auth == "" {
+		return true
+	}
+	value, user := h.authUser(r)
+	if h.auth == "form" {
+		if r.URL.Path == "/login" {
+			h.pageLogin(w, r, rs)
+			return false
+		}
+		if user == "" {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return false
+		}
+	} else if user == "" {
+		name, password, ok := r.BasicAuth()
+		if !ok || !h.passwords.Check(name, password) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="gocards", charset="UTF-8"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return false
+		}
+		value, user = h.setAuthCookie(w, r, name), name
 	}
+	if !h.checkCsrf(w, r, rs, value) {
+		return false
+	}
+	if h.auth == "form" {
+		if r.URL.Path == "/logout" && r.Method == "POST" {
+			http.SetCookie(w, &http.Cookie{Name: authCookie, Value: "", Path: "/", MaxAge: -1})
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return false
+		}
+	}
+	rs.user = user
+	return true
+}
+
+// checkCsrf puts the CSRF token made from the cookie value passed in into the request state.
+// Returns false and writes an error if the request is a POST without that CSRF token.
+func (h *httpHandler) checkCsrf(w http.ResponseWriter, r *http.Request, rs *requestState, value string) bool {
+	rs.csrf = gocards.Signature(h.authKey, "csrf|"+value)
+	if r.Method == "POST" {
+		r.ParseForm()
+		if subtle.ConstantTimeCompare([]byte(r.FormValue("csrf")), []byte(rs.csrf)) != 1 {
+			http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+			return false
+		}
+	}
+	return true
+}
+
+// authUser returns the value of the auth cookie of a request and the user it is for.
+// Empty strings are returned if there is no cookie or it is not signed right, has expired or is for an unknown user.
+func (h *httpHandler) authUser(r *http.Request) (string, string) {
+	cookie, err := r.Cookie(authCookie)
+	if err != nil {
+		return "", ""
+	}
+	value, ok := gocards.Verify(h.authKey, cookie.Value)
+	if !ok {
+		return "", ""
+	}
+	data := strings.Split(value, "|")
+	if len(data) != 2 {
+		return "", ""
+	}
+	expires, err := strconv.ParseInt(data[1], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return "", ""
+	}
+	if _, ok := h.passwords[data[0]]; !ok {
+		return "", ""
+	}
+	return cookie.Value, data[0]
+}
+
+// setAuthCookie sets a signed cookie with the name of the user that expires after authTimeout.
+// Returns the value of the cookie.
+func (h *httpHandler) setAuthCookie(w http.ResponseWriter, r *http.Request, user string) string {
+	expires := time.Now().Add(authTimeout)
+	value := gocards.Sign(h.authKey, fmt.Sprintf("%s|%d", user, expires.Unix()))
+	http.SetCookie(w, &http.Cookie{
+		Name:     authCookie,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return value
+}
+
+// pageLogin displays the login page.
+// When the login form is posted, the user name and password are checked and the user is logged in.
+// Users are not logged in yet, so the CSRF token of the login form is made from the login cookie.
+// The login cookie has a random value and is set when the login page is first displayed.
+func (h *httpHandler) pageLogin(w http.ResponseWriter, r *http.Request, rs *requestState) {
+	value := ""
+	if cookie, err := r.Cookie(loginCookie); err == nil {
+		value = cookie.Value
+	}
+	if value == "" {
+		if r.Method == "POST" {
+			http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+			return
+		}
+		b := make([]byte, 16)
+		_, err := cryptorand.Read(b)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		value = hex.EncodeToString(b)
+		http.SetCookie(w, &http.Cookie{
+			Name:     loginCookie,
+			Value:    value,
+			Path:     "/login",
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	if !h.checkCsrf(w, r, rs, value) {
+		return
+	}
+	if r.Method == "POST" {
+		user := r.FormValue("user")
+		if h.passwords.Check(user, r.FormValue("password")) {
+			h.setAuthCookie(w, r, user)
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+		h.render(rs, "login", &loginPage{pageAuth{}, "Wrong user or password"})
+		return
+	}
+	h.render(rs, "login", &loginPage{pageAuth{}, ""})
+}
+
+// loginPage is the data for the login page.
+type loginPage struct {
+	pageAuth
+	Msg string
 }
 
 // cardSet is called by ServeHttp when a request path is for a card set.
 // This path is requested by clicking a link on the main page.
 // This path is also requested by clicking some of the buttons on a card's page.
 // The first request to a card set when doing cards is a GET.
-// Populates the card set session in the httpHandler on a GET.
+// Starts a new card set session on a GET.
 // When doing cards, requests are POSTs.
-func (h *httpHandler) cardSet(w http.ResponseWriter, r *http.Request) {
+// The id of the session is in the url that forms on card pages are posted to.
+func (h *httpHandler) cardSet(w http.ResponseWriter, r *http.Request, rs *requestState) {
 	var err error
+	var session *cardSetSession
+	r.ParseForm()
 	if r.Method == "GET" {
-		err = h.populateCardSetSession(r)
-		if err != nil {
-			pageError(w, err)
-			return
-		}
+		session, err = h.populateCardSetSession(r, rs)
+	} else {
+		session, err = h.getCardSetSession(r.FormValue("session"), rs.user)
 	}
-	r.ParseForm()
+	if err != nil {
+		h.pageError(w, rs, err)
+		return
+	}
+	// the session may have been started in a profile other than the one the browser uses now
+	rs.profile = session.profile
+	url := r.URL.Path + "?session=" + session.id
 	if r.Method == "POST" {
-		f, err := h.handleCardSetPost(w, r)
+		f, err := h.handleCardSetPost(w, r, rs, session, url)
 		if err != nil {
-			pageError(w, err)
+			h.pageError(w, rs, err)
 			return
 		}
 		if f != nil {
@@ -176,21 +737,26 @@ func (h *httpHandler) cardSet(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
-	cards, msg, err := h.getCards()
+	cards, msg, err := h.getCards(session)
 	if err != nil {
-		pageError(w, err)
+		h.pageError(w, rs, err)
 		return
 	}
 	if len(cards) == 0 {
-		pageMessage(w, "No cards found")
+		h.pageMessage(w, rs, "No cards found")
 		return
 	}
 	card, err := h.getCard(cards)
 	if err != nil {
-		pageError(w, err)
+		h.pageError(w, rs, err)
 		return
 	}
-	pageCardFront(w, r.URL.Path, card, msg)
+	cardSet := session.cardSetOf(card)
+	if session.cardType == "quiz" {
+		h.pageQuiz(w, rs, url, cardSet.Id, card, h.quizOptions(cardSet, card), msg)
+		return
+	}
+	h.pageCardFront(w, rs, url, cardSet.Id, card, msg, session.typeAnswer)
 }
 
 // getCard returns a *gocards.Card from the list of cards passed in.
@@ -206,28 +772,14 @@ func (h *httpHandler) getCard(cards []*gocards.Card) (*gocards.Card, error) {
 // Returns an error if one occurs.
 // At most 10 cards are returned.
 // The list returned is passed to the getCard method to get the card to use.
-func (h *httpHandler) getCards() ([]*gocards.Card, string, error) {
-	if h.session == nil {
-		return nil, "", errors.New("Session not defined")
-	}
-	var cards []*gocards.Card
-	var msg string
-	if h.session.cardType == "all" {
-		cards = h.removeCardsDone(h.session.cardSet.Cards)
-		msg = fmt.Sprintf("all: %d done: %d", len(cards), len(h.session.cardsDone))
-	} else if h.session.cardType == "due_new" {
-		cards = gocards.GetDueOrNewCards(h.session.cardSet.Cards)
-		msg = fmt.Sprintf("due or new: %d done: %d", len(cards), len(h.session.cardsDone))
-	} else if h.session.cardType == "due" {
-		cards = gocards.GetDueCards(h.session.cardSet.Cards)
-		msg = fmt.Sprintf("due: %d done: %d", len(cards), len(h.session.cardsDone))
-	} else if h.session.cardType == "new" {
-		cards = gocards.GetIntervalCards(h.session.cardSet.Cards, 0)
-		msg = fmt.Sprintf("new: %d done: %d", len(cards), len(h.session.cardsDone))
-	} else {
-		cards = h.removeCardsDone(gocards.GetIntervalCards(h.session.cardSet.Cards, h.session.cardInterval))
-		msg = fmt.Sprintf("interval %d day(s): %d done: %d", h.session.cardInterval, len(cards), len(h.session.cardsDone))
+func (h *httpHandler) getCards(session *cardSetSession) ([]*gocards.Card, string, error) {
+	now := time.Now()
+	cardLists := [][]*gocards.Card{}
+	for _, cardSet := range session.cardSets {
+		cardLists = append(cardLists, h.sessionCards(session, cardSet, now))
 	}
+	cards := interleave(cardLists)
+	msg := fmt.Sprintf("%s: %d done: %d", session.description(), len(cards), len(session.cardsDone))
 	if len(cards) <= 10 {
 		return cards, msg, nil
 	}
@@ -256,157 +808,514 @@ func (h *httpHandler) getCards() ([]*gocards.Card, string, error) {
 	return cardSubset, msg, nil
 }
 
+// interleave returns the cards in the lists passed in taking one card from each list in turn.
+// This mixes the cards from each card set in sessions with more than one card set.
+func interleave(cardLists [][]*gocards.Card) []*gocards.Card {
+	cards := []*gocards.Card{}
+	for i := 0; ; i++ {
+		added := false
+		for _, cardList := range cardLists {
+			if i < len(cardList) {
+				cards = append(cards, cardList[i])
+				added = true
+			}
+		}
+		if !added {
+			return cards
+		}
+	}
+}
+
+// sessionCards returns the cards in a card set to do in the session.
+// Spaced repetition sessions are limited by the daily limits of the card set.
+func (h *httpHandler) sessionCards(session *cardSetSession, cardSet *gocards.CardSet, now time.Time) []*gocards.Card {
+	cards := cardSet.Cards
+	if session.tag != "" {
+		cards = gocards.GetTaggedCards(cards, session.tag)
+	}
+	if session.cardType == "all" {
+		return h.removeCardsDone(session, cards)
+	} else if session.cardType == "due_new" || session.cardType == "quiz" {
+		return cardSet.LimitCards(gocards.GetDueOrNewCards(cards), now)
+	} else if session.cardType == "due" {
+		return cardSet.LimitCards(gocards.GetDueCards(cards), now)
+	} else if session.cardType == "new" {
+		return cardSet.LimitCards(gocards.GetIntervalCards(cards, 0), now)
+	} else if session.cardType == "suspended" {
+		return h.removeCardsDone(session, gocards.GetSuspendedCards(cards))
+	}
+	return h.removeCardsDone(session, gocards.GetIntervalCards(cards, session.cardInterval))
+}
+
 // handleCardSetPost is called when a POST happens on a card set path.
 // Processes "back" button pushes.
-// Processes "correct" and "incorrect" button pushes.
+// Processes "again", "hard", "good" and "easy" button pushes.
 // Processes "skip" button pushes.
+// Processes "suspend", "unsuspend" and "bury" button pushes.
+// Processes quiz option clicks, the right option grades the card "good" and any other option "again".
+// Suspended cards are not done again until they are unsuspended.
+// Buried cards are not done again until tomorrow.
 // For "back" button pushes this retuns a function to call to display the back of the card.
 // In all other cases, nil is returned.
 // An error is returned if one occurs.
-func (h *httpHandler) handleCardSetPost(w http.ResponseWriter, r *http.Request) (func(), error) {
-	action, card, err := h.parseCardSetPost(r)
+func (h *httpHandler) handleCardSetPost(w http.ResponseWriter, r *http.Request, rs *requestState, session *cardSetSession, url string) (func(), error) {
+	action, cardSet, card, err := h.parseCardSetPost(r, session)
 	if err != nil {
 		return nil, err
 	}
 	if action == "back" {
 		f := func() {
-			pageCardBack(w, r.URL.Path, card, r.FormValue("msg"))
+			grades := gradeNames(cardSet)
+			if session.typeAnswer {
+				answer := r.FormValue("answer")
+				suggested := suggestedGradeName(cardSet, gocards.SuggestGrade(answer, card.Answer()))
+				h.pageCardBack(w, rs, url, cardSet.Id, card, grades, r.FormValue("shown"), r.FormValue("msg"), answer, suggested)
+			} else {
+				h.pageCardBack(w, rs, url, cardSet.Id, card, grades, r.FormValue("shown"), r.FormValue("msg"), "", "")
+			}
 		}
 		return f, nil
 	} else if action == "review" {
 		review, now := r.FormValue("review"), time.Now()
-		if review == "correct" {
-			if h.session.spacedRepetition {
-				h.save[h.session.cardSet.Id] = true
-				card.LastReviewTime = now
-				card.CorrectCount += 1
-				if card.Interval() > 0 {
-					h.session.cardsDone[card.Md5] = true
-				}
-			} else {
-				h.session.cardsDone[card.Md5] = true
-			}
-		} else if review == "incorrect" {
-			if h.session.spacedRepetition {
-				h.save[h.session.cardSet.Id] = true
-				card.LastReviewTime = now
-				card.CorrectCount = 0
-			}
-		} else if review == "skip" {
+		if review == "skip" {
 			// fall through
 		} else {
-			return nil, errors.New("Inavlid review")
+			grade, err := gocards.ParseGrade(review)
+			if err != nil {
+				return nil, errors.New("Inavlid review")
+			}
+			h.review(session, cardSet, card, grade, now, timeSpent(r.FormValue("shown"), now))
+		}
+	} else if action == "quiz" {
+		now := time.Now()
+		grade := gocards.Again
+		if r.FormValue("choice") == card.Md5 {
+			grade = gocards.Good
 		}
+		h.review(session, cardSet, card, grade, now, timeSpent(r.FormValue("shown"), now))
+		f := func() {
+			h.pageQuizResult(w, rs, url, cardSet.Id, card, grade == gocards.Good, r.FormValue("msg"))
+		}
+		return f, nil
 	} else if action == "skip" {
 		// fall through
+	} else if action == "suspend" || action == "unsuspend" {
+		card.Suspended = action == "suspend"
+		session.profile.save[cardSet.Id] = true
+		session.cardsDone[card] = true
+	} else if action == "bury" {
+		card.Bury(time.Now())
+		session.profile.save[cardSet.Id] = true
+		session.cardsDone[card] = true
 	} else {
 		return nil, errors.New("Invalid action")
 	}
 	return nil, nil
 }
 
+// review grades a card done in the session.
+// In spaced repetition sessions, the card is reviewed and is done once it has an interval.
+// In other sessions, the card is done once it is not graded "again".
+func (h *httpHandler) review(session *cardSetSession, cardSet *gocards.CardSet, card *gocards.Card, grade gocards.Grade, now time.Time, duration time.Duration) {
+	if session.spacedRepetition {
+		session.profile.save[cardSet.Id] = true
+		cardSet.Review(card, grade, now, duration)
+		if card.Interval() > 0 {
+			session.cardsDone[card] = true
+		}
+	} else if grade != gocards.Again {
+		session.cardsDone[card] = true
+	}
+}
+
+// quizOptions returns the options for a quiz question in a random order.
+// The options are the card and up to three cards from the card set with answers like the card's answer.
+func (h *httpHandler) quizOptions(cardSet *gocards.CardSet, card *gocards.Card) []*gocards.Card {
+	options := append(gocards.Distractors(card, cardSet.Cards, 3), card)
+	rand.Shuffle(len(options), func(i, j int) {
+		options[i], options[j] = options[j], options[i]
+	})
+	return options
+}
+
+// timeSpent returns the time since a card was shown.
+// The time the card was shown is passed in as a string of nanoseconds since the unix epoch.
+// A zero duration is returned if the string is not valid.
+func timeSpent(shown string, now time.Time) time.Duration {
+	nanoseconds, err := strconv.ParseInt(shown, 10, 64)
+	if err != nil {
+		return 0
+	}
+	duration := now.Sub(time.Unix(0, nanoseconds))
+	if duration < 0 {
+		return 0
+	}
+	return duration
+}
+
 // pageMain displays the main page of the web app.
 // The URL for this page is just "/".
 // The page is a table with rows of card sets and links to do cards.
+// Card sets are shown in a tree of the folders they are in.
+// Each folder has a row with the stats of all the card sets under it and links to do their cards.
+// Clicking the "-" or "+" next to a folder hides or shows what is in it.
 // The page also has a "save" button that will save data for cards that need to be written to disk.
-func (h *httpHandler) pageMain(w http.ResponseWriter, r *http.Request) {
+// The page also shows how many new cards and reviews are left to do today.
+// The "all due" link does the due and new cards from all card sets in one session.
+// The stats on the page are for the profile being used.
+// The page has a form to change the profile unless logging in is turned on.
+func (h *httpHandler) pageMain(w http.ResponseWriter, r *http.Request, rs *requestState) {
 	msg := ""
-	if len(h.save) > 0 {
+	if len(rs.profile.save) > 0 {
 		msg = "needs saving"
 	}
-	fmt.Fprintf(w, "<html><head></head><body>\n")
-	fmt.Fprintf(w, "<table><tr><td>\n")
-	fmt.Fprintf(w, "<form action=\"/\" method=\"POST\">\n"+
-		"<input type=\"hidden\" name=\"action\" value=\"save\">\n"+
-		"<input type=\"submit\" value=\"Save\">\n"+
-		"</form>\n")
-	fmt.Fprintf(w, "    </td><td>\n")
-	fmt.Fprintf(w, "        <form><label>%s</label></form>\n", msg)
-	fmt.Fprintf(w, "    </td></tr>\n")
-	fmt.Fprintf(w, "</table>\n")
-	fmt.Fprintf(w, "<table border=\"1\">\n")
-	fmt.Fprintf(w, "<tr align=\"center\">\n")
-	fmt.Fprintf(w, "    <td>Card Set</td>\n")
-	fmt.Fprintf(w, "    <td>Total</td>\n")
-	fmt.Fprintf(w, "    <td>Blank</td>\n")
-	fmt.Fprintf(w, "    <td>New</td>\n")
-	fmt.Fprintf(w, "    <td>Due</td>\n")
-
-	intervalValue := -1
-	for i := 0; i < len(gocards.Intervals); i++ {
-		if intervalValue != gocards.Intervals[i] {
-			intervalValue = gocards.Intervals[i]
-			fmt.Fprintf(w, "    <td>%d</td>\n", intervalValue)
-		}
-	}
-	fmt.Fprintf(w, "</tr>\n")
-
-	for _, cardSet := range h.cardSets {
-		stats := cardSet.Stats()
-		fmt.Fprintf(w, "<tr align=\"center\">\n")
-		fmt.Fprintf(w, "    <td bgcolor=\"#D3D3D3\"><a href=\"%s\">%s</a></td>\n", stats.Id, stats.Id)
-		fmt.Fprintf(w, "    <td><a href=\"%s/all\">%d</a></td>\n", stats.Id, stats.TotalCount)
-		fmt.Fprintf(w, "    <td>%d</td>\n", stats.BlankCount)
-		fmt.Fprintf(w, "    <td bgcolor=\"#D3D3D3\"><a href=\"%s/new\">%d</a></td>\n", stats.Id, stats.NewCount)
-		fmt.Fprintf(w, "    <td bgcolor=\"#D3D3D3\"><a href=\"%s/due\">%d</a></td>\n", stats.Id, stats.DueCount)
-		intervalValue := -1
-		for i := 0; i < len(gocards.Intervals); i++ {
-			if intervalValue != gocards.Intervals[i] {
-				intervalValue = gocards.Intervals[i]
-				count, ok := stats.IntervalCount[intervalValue]
-				if !ok {
-					count = 0
+	now := time.Now()
+	today := fmt.Sprintf("new today: %s reviews today: %s",
+		quotaString(rs.profile.quota.TotalNew(now), rs.profile.quota.NewPerDay),
+		quotaString(rs.profile.quota.TotalReviews(now), rs.profile.quota.ReviewsPerDay))
+
+	stats := map[string]*gocards.CardSetStats{}
+	for _, cardSet := range rs.profile.cardSets {
+		stats[cardSet.Id] = cardSet.Stats()
+	}
+	rows := []*mainRow{}
+	// card sets are sorted by id so the card sets in a folder are next to each other
+	shown := map[string]bool{}
+	for _, cardSet := range rs.profile.cardSets {
+		folders := cardSet.Folders()
+		for depth, folder := range folders {
+			if shown[folder] {
+				continue
+			}
+			shown[folder] = true
+			folderStats := gocards.NewCardSetStats(folder)
+			for _, c := range gocards.GetFolderCardSets(rs.profile.cardSets, folder) {
+				folderStats.Add(stats[c.Id])
+			}
+			rows = append(rows, newMainRow(folder, "folder", path.Base(folder)+"/", depth, true, folderStats, "", ""))
+		}
+		rows = append(rows, newMainRow(cardSet.Id, cardSet.Id, path.Base(cardSet.Id), len(folders), false, stats[cardSet.Id],
+			leftString(cardSet.NewLeft(now)), leftString(cardSet.ReviewsLeft(now))))
+	}
+	var profiles []string
+	if h.auth == "" {
+		var err error
+		profiles, err = h.profileNames()
+		if err != nil {
+			h.pageError(w, rs, err)
+			return
+		}
+	}
+	h.render(rs, "main", &mainPage{pageAuth{}, msg, today, gocards.Tags(rs.profile.cardSets), h.intervalColumns(rs.profile.cardSets), rows, rs.profile.name, profiles})
+}
+
+// mainPage is the data for the main page.
+type mainPage struct {
+	pageAuth
+	Msg       string
+	Today     string
+	Tags      []string
+	Intervals []int
+	Rows      []*mainRow
+	Profile   string
+	Profiles  []string
+}
+
+// mainRow is a row of the table on the main page for a card set or folder.
+// The rows of card sets and folders in a folder are indented by their depth in the folder tree.
+// The links in the row start sessions for URL, with the folder in the query string for folder rows.
+type mainRow struct {
+	Path        string
+	URL         string
+	Name        string
+	Indent      int
+	Folder      bool
+	Stats       *gocards.CardSetStats
+	NewLeft     string
+	ReviewsLeft string
+	Quiz        int
+}
+
+func newMainRow(rowPath, url, name string, depth int, folder bool, stats *gocards.CardSetStats, newLeft, reviewsLeft string) *mainRow {
+	return &mainRow{rowPath, url, name, depth * 2, folder, stats, newLeft, reviewsLeft, stats.NewCount + stats.DueCount}
+}
+
+// quotaString returns a count of cards done today for display.
+// The limit is included if there is one.
+func quotaString(count, limit int) string {
+	if limit == 0 {
+		return strconv.Itoa(count)
+	}
+	return fmt.Sprintf("%d/%d", count, limit)
+}
+
+// leftString returns the number of cards left to do today for display.
+// An empty string is returned if there is no limit.
+func leftString(left int) string {
+	if left < 0 {
+		return ""
+	}
+	return strconv.Itoa(left)
+}
+
+// intervalColumns returns the intervals shown as columns on the main page.
+// These are the default intervals and any other intervals card sets group their cards by.
+func (h *httpHandler) intervalColumns(cardSets []*gocards.CardSet) []int {
+	seen := map[int]bool{}
+	intervals := []int{}
+	add := func(interval int) {
+		if !seen[interval] {
+			seen[interval] = true
+			intervals = append(intervals, interval)
+		}
+	}
+	for _, interval := range gocards.Intervals {
+		add(interval)
+	}
+	for _, cardSet := range cardSets {
+		for _, interval := range cardSet.Scheduler.Buckets() {
+			add(interval)
+		}
+	}
+	sort.Ints(intervals)
+	return intervals
+}
+
+// suggestedGradeName returns the name of the button for a suggested grade.
+func suggestedGradeName(cardSet *gocards.CardSet, grade gocards.Grade) string {
+	if cardSet.Config.Grading == "two" {
+		if grade == gocards.Again {
+			return "incorrect"
+		}
+		return "correct"
+	}
+	return grade.String()
+}
+
+// gradeNames returns the names of the buttons used to grade cards in the card set.
+func gradeNames(cardSet *gocards.CardSet) []string {
+	if cardSet.Config.Grading == "two" {
+		return []string{"correct", "incorrect"}
+	}
+	names := []string{}
+	for _, grade := range gocards.Grades {
+		names = append(names, grade.String())
+	}
+	return names
+}
+
+// pageLeeches displays the cards in all card sets that are leeches.
+// The URL for this page is "/leeches".
+// Leeches are cards that have lapsed as many times as the leech threshold of their card set.
+// The page lists the card file each leech is in so the card can be rewritten.
+func (h *httpHandler) pageLeeches(w http.ResponseWriter, r *http.Request, rs *requestState) {
+	leeches := []*leechRow{}
+	for _, cardSet := range rs.profile.cardSets {
+		for _, card := range cardSet.Leeches() {
+			leeches = append(leeches, &leechRow{cardSet, card})
+		}
+	}
+	h.render(rs, "leeches", &leechesPage{pageAuth{}, leeches})
+}
+
+// leechesPage is the data for the leeches page.
+type leechesPage struct {
+	pageAuth
+	Leeches []*leechRow
+}
+
+type leechRow struct {
+	CardSet *gocards.CardSet
+	Card    *gocards.Card
+}
+
+// statsDays is the number of days shown in the charts on the stats page.
+const statsDays = 30
+
+// pageStats displays statistics for card sets.
+// The URL for this page is "/stats" for all card sets or "/stats?set=<card set id>" for one card set.
+// The page shows reviews per day and retention for the last 30 days from the review logs.
+// The page also shows the number of cards due each day for the next 30 days
+// and the number of cards at each interval.
+// Charts are drawn with inline svg by the "barChart" template.
+func (h *httpHandler) pageStats(w http.ResponseWriter, r *http.Request, rs *requestState) {
+	cardSets, title := rs.profile.cardSets, "all card sets"
+	cardSetId := r.URL.Query().Get("set")
+	if cardSetId != "" {
+		cardSets = nil
+		for _, c := range rs.profile.cardSets {
+			if cardSetId == c.Id {
+				cardSets = []*gocards.CardSet{c}
+			}
+		}
+		if cardSets == nil {
+			h.pageMessage(w, rs, "Invalid card set")
+			return
+		}
+		title = cardSetId
+	}
+
+	now := time.Now()
+	stats := gocards.NewCardSetStats(title)
+	reviews := make([]float64, statsDays)
+	learned := make([]float64, statsDays)
+	recalled := make([]float64, statsDays)
+	forecast := make([]float64, statsDays)
+	_, total := gocards.ForecastCardSets(cardSets, statsDays, now)
+	for i, count := range total {
+		forecast[i] = float64(count)
+	}
+	for _, cardSet := range cardSets {
+		stats.Add(cardSet.Stats())
+		entries, err := cardSet.ReviewLog()
+		if err != nil {
+			h.pageError(w, rs, err)
+			return
+		}
+		for _, entry := range entries {
+			i := statsDays - 1 - gocards.DaysBetween(entry.Time, now)
+			if i < 0 || i >= statsDays {
+				continue
+			}
+			reviews[i] += 1
+			// retention is only for cards that had been learned
+			if entry.PreviousInterval > 0 {
+				learned[i] += 1
+				if entry.Grade != gocards.Again {
+					recalled[i] += 1
 				}
-				fmt.Fprintf(w, "    <td><a href=\"%s/%d\">%d</a></td>\n", stats.Id, intervalValue, count)
 			}
 		}
-		fmt.Fprintf(w, "</tr>\n")
 	}
-	fmt.Fprintf(w, "</table>\n")
-	fmt.Fprintf(w, "</body></html>\n")
+
+	retention := make([]float64, statsDays)
+	totalLearned, totalRecalled := 0.0, 0.0
+	for i := range retention {
+		if learned[i] > 0 {
+			retention[i] = 100 * recalled[i] / learned[i]
+		}
+		totalLearned += learned[i]
+		totalRecalled += recalled[i]
+	}
+	totalRetention := "none"
+	if totalLearned > 0 {
+		totalRetention = fmt.Sprintf("%.1f%%", 100*totalRecalled/totalLearned)
+	}
+
+	pastLabels, futureLabels := make([]string, statsDays), make([]string, statsDays)
+	for i := 0; i < statsDays; i += 5 {
+		pastLabels[statsDays-1-i] = now.AddDate(0, 0, -i).Format("01-02")
+		futureLabels[i] = now.AddDate(0, 0, i).Format("01-02")
+	}
+	intervals := h.intervalColumns(rs.profile.cardSets)
+	intervalCounts, intervalLabels := []float64{}, []string{}
+	for _, interval := range intervals {
+		intervalCounts = append(intervalCounts, float64(stats.IntervalCount[interval]))
+		intervalLabels = append(intervalLabels, strconv.Itoa(interval))
+	}
+
+	charts := []*barChart{
+		newBarChart("Reviews per day", reviews, pastLabels),
+		newBarChart("Retention per day (%)", retention, pastLabels),
+		newBarChart("Cards due per day", forecast, futureLabels),
+		newBarChart("Cards per interval (days)", intervalCounts, intervalLabels),
+	}
+	h.render(rs, "stats", &statsPage{pageAuth{}, title, rs.profile.cardSets, stats, statsDays, totalRetention, charts})
+}
+
+// statsPage is the data for the stats page.
+type statsPage struct {
+	pageAuth
+	Title     string
+	CardSets  []*gocards.CardSet
+	Stats     *gocards.CardSetStats
+	Days      int
+	Retention string
+	Charts    []*barChart
+}
+
+// barChart is an svg bar chart.
+// Each bar has the label with the same index under it, empty labels are not shown.
+type barChart struct {
+	Title  string
+	Width  int
+	Height int
+	AxisY  int
+	Bars   []*bar
+}
+
+type bar struct {
+	Value  float64
+	Label  string
+	X      int
+	Y      int
+	Width  int
+	Height int
+	Center int
+	ValueY int
+	LabelY int
 }
 
-// pagemessage displays a webpage with a message on it.
-func pageMessage(w http.ResponseWriter, msg string) {
-	fmt.Fprintf(w, "<html><head></head><body>\n")
-	fmt.Fprintf(w, "<table><tr><td>\n")
-	fmt.Fprintf(w, "<form action=\"/\" method=\"POST\">\n"+
-		"<input type=\"hidden\" name=\"action\" value=\"main\">\n"+
-		"<input type=\"submit\" value=\"main\">\n"+
-		"</form>\n")
-	fmt.Fprintf(w, "</td><td><form><label>%s</label></form></td>\n", msg)
-	fmt.Fprintf(w, "</tr></table>\n")
-	fmt.Fprintf(w, "</body></html>\n")
+func newBarChart(title string, values []float64, labels []string) *barChart {
+	barWidth, chartHeight, labelHeight := 24, 150, 20
+	maxValue := 0.0
+	for _, value := range values {
+		maxValue = math.Max(maxValue, value)
+	}
+	chart := &barChart{title, barWidth * len(values), chartHeight + labelHeight, chartHeight, []*bar{}}
+	for i, value := range values {
+		x := i * barWidth
+		height := 0
+		if value > 0 {
+			// leave room over the tallest bar for its value
+			height = int(value / maxValue * float64(chartHeight-15))
+		}
+		label := ""
+		if i < len(labels) {
+			label = labels[i]
+		}
+		chart.Bars = append(chart.Bars, &bar{value, label, x + 2, chartHeight - height, barWidth - 4, height,
+			x + barWidth/2, chartHeight - height - 3, chartHeight + labelHeight - 5})
+	}
+	return chart
 }
 
 // parseCardSetPost parses POST requests to card set urls.
 // Returns a string that is the "action" value of the POST.
+// Returns the card set the card being done is in as a *gocards.CardSet.
 // Returns the card being done as a *gocards.Card.
 // Returns an error if one occurs.
-func (h *httpHandler) parseCardSetPost(r *http.Request) (string, *gocards.Card, error) {
-	if h.session == nil {
-		return "", nil, errors.New("Session not defined")
-	}
+func (h *httpHandler) parseCardSetPost(r *http.Request, session *cardSetSession) (string, *gocards.CardSet, *gocards.Card, error) {
 	action := r.FormValue("action")
 	if action == "" {
-		return "", nil, errors.New("Action not defined")
+		return "", nil, nil, errors.New("Action not defined")
 	}
 	md5 := r.FormValue("md5")
 	if md5 == "" {
-		return "", nil, errors.New("MD5 not defined")
+		return "", nil, nil, errors.New("MD5 not defined")
+	}
+	cardSetId := r.FormValue("set")
+	var cardSet *gocards.CardSet
+	for _, c := range session.cardSets {
+		if cardSetId == c.Id {
+			cardSet = c
+		}
+	}
+	if cardSet == nil {
+		return "", nil, nil, errors.New("Invalid card set")
 	}
 	var card *gocards.Card
 	found := false
-	for _, card = range h.session.cardSet.Cards {
+	for _, card = range cardSet.Cards {
 		if md5 == card.Md5 {
 			found = true
 			break
 		}
 	}
 	if !found {
-		return "", nil, errors.New("Invalid MD5")
+		return "", nil, nil, errors.New("Invalid MD5")
 	}
-	return action, card, nil
+	return action, cardSet, card, nil
 }
 
 // isInt returns true of the string is an integer.
@@ -416,7 +1325,7 @@ func isInt(s string) bool {
 }
 
 // parseCardSetUrl parses the url for a card set.
-// Returns card set id.
+// Returns the card set id or other path the session is for.
 // Returns a bool set to true if the URL is for a spaced repetition session.
 // Returns string for display that is the type of cards for this session.
 // Retruns a non-negative integer if this session is for a particular interval.
@@ -440,6 +1349,13 @@ func (h *httpHandler) parseCardSetUrl(r *http.Request) (string, bool, string, in
 		cardSetId = strings.Join(parts[:len(parts)-1], "/")
 		cardType = "due"
 		spacedRepetition = true
+	} else if lastPart == "quiz" {
+		cardSetId = strings.Join(parts[:len(parts)-1], "/")
+		cardType = "quiz"
+		spacedRepetition = true
+	} else if lastPart == "suspended" {
+		cardSetId = strings.Join(parts[:len(parts)-1], "/")
+		cardType = "suspended"
 	} else if isInt(lastPart) { // is number
 		cardSetId = strings.Join(parts[:len(parts)-1], "/")
 		cardInterval, err = strconv.Atoi(lastPart)
@@ -456,33 +1372,102 @@ func (h *httpHandler) parseCardSetUrl(r *http.Request) (string, bool, string, in
 
 // populateCardSetSession populates the session value in the http handler.
 // Session information is determined by parsing the URL.
+// A "mode=type" query string starts a session where answers are typed in.
+// A path of "tag" with a "tag" query string starts a session with the cards in all card sets that have the tag.
+// The tag is in the query string so tags like "new" or "2" are not read as the type of session.
+// A path of "folder" with a "folder" query string starts a session with the cards in all card sets under the folder.
+// A path of "all-due" starts a spaced repetition session with the due and new cards in all card sets.
 // Should only be called on the initial GET of session of doing a card set.
+// Each session gets a random id and sessions that have not been used for a while are removed.
+// Returns the new session.
 // Returns an error if one occurs.
-func (h *httpHandler) populateCardSetSession(r *http.Request) error {
+func (h *httpHandler) populateCardSetSession(r *http.Request, rs *requestState) (*cardSetSession, error) {
 	cardSetId, spacedRepetition, cardType, cardInterval, err := h.parseCardSetUrl(r)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	var cardSet *gocards.CardSet
-	for _, c := range h.cardSets {
+	cardSets := []*gocards.CardSet{}
+	tag := ""
+	for _, c := range rs.profile.cardSets {
 		if cardSetId == c.Id {
-			cardSet = c
+			cardSets = append(cardSets, c)
 		}
 	}
-	if cardSet == nil {
-		return errors.New("Invalid card set")
+	if len(cardSets) == 0 && cardSetId == "tag" {
+		tag = r.URL.Query().Get("tag")
+		if tag == "" {
+			return nil, errors.New("Tag not defined")
+		}
+		cardSets = rs.profile.cardSets
+	} else if len(cardSets) == 0 && cardSetId == "folder" {
+		cardSets = gocards.GetFolderCardSets(rs.profile.cardSets, r.URL.Query().Get("folder"))
+	} else if len(cardSets) == 0 && cardSetId == "all-due" {
+		cardSets = rs.profile.cardSets
+	}
+	if len(cardSets) == 0 {
+		return nil, errors.New("Invalid card set")
+	}
+	id, err := newSessionId()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for sessionId, session := range h.sessions {
+		if now.Sub(session.lastUsed) > sessionTimeout {
+			delete(h.sessions, sessionId)
+		}
+	}
+	typeAnswer := r.URL.Query().Get("mode") == "type"
+	session := &cardSetSession{cardSets, spacedRepetition, cardType, cardInterval, map[*gocards.Card]bool{}, typeAnswer, tag, id, now, rs.profile, rs.user}
+	h.sessions[id] = session
+	return session, nil
+}
+
+// getCardSetSession returns the session with the id passed in for the user passed in.
+// Returns an error if there is no session with that id for the user or the session has expired.
+func (h *httpHandler) getCardSetSession(id string, user string) (*cardSetSession, error) {
+	session, ok := h.sessions[id]
+	if !ok || session.user != user {
+		return nil, errors.New("Session not defined")
+	}
+	now := time.Now()
+	if now.Sub(session.lastUsed) > sessionTimeout {
+		delete(h.sessions, id)
+		return nil, errors.New("Session expired")
+	}
+	session.lastUsed = now
+	return session, nil
+}
+
+// newSessionId returns a random id for a session.
+func newSessionId() (string, error) {
+	b := make([]byte, 16)
+	_, err := cryptorand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// cardSetOf returns the card set in the session that the card is in.
+func (s *cardSetSession) cardSetOf(card *gocards.Card) *gocards.CardSet {
+	for _, cardSet := range s.cardSets {
+		for _, c := range cardSet.Cards {
+			if c == card {
+				return cardSet
+			}
+		}
 	}
-	h.session = &cardSetSession{cardSet, spacedRepetition, cardType, cardInterval, map[string]bool{}}
 	return nil
 }
 
 // removeCardsDone removes cards from the slice passed in that have been completed in this session.
 // This checks the cardsDone variable in the section to determine if a card has been done.
 // Returns []*gocards.Cards with cards that have not been done yet.
-func (h *httpHandler) removeCardsDone(cards []*gocards.Card) []*gocards.Card {
+func (h *httpHandler) removeCardsDone(session *cardSetSession, cards []*gocards.Card) []*gocards.Card {
 	undone := make([]*gocards.Card, 0)
 	for _, card := range cards {
-		_, ok := h.session.cardsDone[card.Md5]
+		_, ok := session.cardsDone[card]
 		if !ok {
 			undone = append(undone, card)
 		}
@@ -490,12 +1475,12 @@ func (h *httpHandler) removeCardsDone(cards []*gocards.Card) []*gocards.Card {
 	return undone
 }
 
-// saveCardSets saves the data for card sets that need to be written to disk.
+// saveCardSets saves the data for card sets of the profile that need to be written to disk.
 // returns an error if one occurs.
-func (h *httpHandler) saveCardSets() error {
-	for cardSetId := range h.save {
+func (p *profile) saveCardSets() error {
+	for cardSetId := range p.save {
 		var cardSet *gocards.CardSet
-		for _, c := range h.cardSets {
+		for _, c := range p.cardSets {
 			if cardSetId == c.Id {
 				cardSet = c
 			}
@@ -514,15 +1499,67 @@ func (h *httpHandler) saveCardSets() error {
 			return err
 		}
 	}
-	h.save = map[string]bool{}
+	if len(p.save) > 0 {
+		err := os.MkdirAll(p.path, 0755)
+		if err != nil {
+			return err
+		}
+		err = p.quota.Save(filepath.Join(p.path, "dailyCounts"), time.Now())
+		if err != nil {
+			return err
+		}
+	}
+	p.save = map[string]bool{}
+	return nil
+}
+
+// saveProfiles saves the data for card sets of all profiles that need to be written to disk.
+// The mutex must be held when this is called.
+// returns an error if one occurs.
+func (h *httpHandler) saveProfiles() error {
+	for _, p := range h.profiles {
+		err := p.saveCardSets()
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// autosaveProfiles saves the data of all profiles that needs to be written to disk.
+// Errors are printed because there is no web page to show them on.
+// The mutex must be held when this is called.
+func (h *httpHandler) autosaveProfiles() {
+	err := h.saveProfiles()
+	if err != nil {
+		fmt.Printf("Unable to save card sets: %s\n", err)
+	}
+}
+
+// autosaveLoop saves the data of all profiles every autosave seconds until the context is done.
+func (h *httpHandler) autosaveLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(h.autosave) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.mutex.Lock()
+			h.autosaveProfiles()
+			h.mutex.Unlock()
+		}
+	}
+}
+
+// httpClient is used to get web pages so a web site that does not answer can't hold up a request forever.
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
 // getHtmlPage gets the web page for the URL passed in.
 // Returns the body of the page as a string on success.
 // Returns an error if one occurs.
 func getHtmlPage(requestUrl string) (string, error) {
-	resp, err := http.Get(requestUrl)
+	resp, err := httpClient.Get(requestUrl)
 	if err != nil {
 		return "", err
 	}
@@ -537,7 +1574,7 @@ func getHtmlPage(requestUrl string) (string, error) {
 // image makes an image html tag from the image url passed in.
 // Returns a string that is the tag.
 func image(imageUrl string) string {
-	return fmt.Sprintf("<img src=\"%s\">\n", imageUrl)
+	return fmt.Sprintf("<img src=\"%s\">\n", template.HTMLEscapeString(imageUrl))
 }
 
 // useImage filters images to be displayed.
@@ -649,69 +1686,129 @@ func markdownToHTML(markdown string) string {
 	return string(md.Render(doc, renderer))
 }
 
-// cardHtml turns a card side into html.
-// The html is written using the http.ResponseWriter.
-func cardHtml(w http.ResponseWriter, card string) {
-	if strings.HasPrefix(card, "image:") {
-		fmt.Fprint(w, image(card[len("image:"):]))
-	} else if strings.HasPrefix(card, "images:") {
-		fmt.Fprint(w, images(card[len("images:"):]))
-	} else if strings.HasPrefix(card, "wikipedia:") {
-		fmt.Fprint(w, wikipediaImages(card[len("wikipedia:"):]))
-	} else {
-		fmt.Fprint(w, markdownToHTML(card))
+// cardPage is the data for the pages that show a card.
+// Side is the html for the side of the card that is shown.
+// It is made from side by prepare because it can need images from other web sites.
+// Actions are the buttons for suspending and burying the card.
+type cardPage struct {
+	pageAuth
+	URL        string
+	CardSetId  string
+	Card       *gocards.Card
+	Msg        string
+	Shown      string
+	Side       template.HTML
+	Actions    []string
+	TypeAnswer bool
+	Grades     []string
+	Suggested  string
+	Diff       []gocards.DiffPart
+	Options    []*gocards.Card
+	Right      bool
+	side       string
+}
+
+// prepare makes the html for the side of the card that is shown.
+func (p *cardPage) prepare() {
+	if p.side != "" {
+		p.Side = cardHtml(p.side)
 	}
 }
 
-// pageCardBack displays the back of a card.
-func pageCardBack(w http.ResponseWriter, url string, card *gocards.Card, msg string) {
-	fmt.Fprintf(w, "<html><head></head><body>\n")
-	fmt.Fprintf(w, "<table><tr><td>\n")
-	fmt.Fprintf(w, "<form action=\"/\" method=\"POST\">\n"+
-		"<input type=\"hidden\" name=\"action\" value=\"main\">\n"+
-		"<input type=\"submit\" value=\"main\">\n"+
-		"</form>\n")
-	fmt.Fprintf(w, "</td><td>\n")
-	fmt.Fprintf(w, "<form action=\"%s\" method=\"POST\">\n"+
-		"<input type=\"hidden\" name=\"action\" value=\"review\">\n"+
-		"<input type=\"hidden\" name=\"md5\" value=\"%s\">\n"+
-		"<input type=\"submit\" name=\"review\" value=\"correct\">\n"+
-		"<input type=\"submit\" name=\"review\" value=\"incorrect\">\n"+
-		"<input type=\"submit\" name=\"review\" value=\"skip\">\n"+
-		"</form>\n", url, card.Md5)
-	fmt.Fprintf(w, "</td>\n")
-	fmt.Fprintf(w, "<td><form><label>%s</label></form></td>\n", msg)
-	fmt.Fprintf(w, "</tr></table>\n")
-	cardHtml(w, card.Back)
-	fmt.Fprintf(w, "</body></html>\n")
+// newCardPage returns the data for a page that shows a side of a card.
+// The front of the card is shown unless back is true.
+func newCardPage(url string, cardSetId string, card *gocards.Card, msg string, back bool) *cardPage {
+	side := card.Front
+	if back {
+		side = card.Back
+	}
+	actions := []string{"suspend", "bury"}
+	if card.Suspended {
+		actions = []string{"unsuspend"}
+	}
+	page := &cardPage{URL: url, CardSetId: cardSetId, Card: card, Msg: msg, Actions: actions}
+	page.side = clozeMarkdown(card, side, back)
+	page.Shown = strconv.FormatInt(time.Now().UnixNano(), 10)
+	return page
 }
 
 // pageCardFront displays the front of a card.
-func pageCardFront(w http.ResponseWriter, url string, card *gocards.Card, msg string) {
-	fmt.Fprintf(w, "<html><head></head><body>\n")
-	fmt.Fprintf(w, "<table><tr><td>\n")
-	fmt.Fprintf(w, "<form action=\"/\" method=\"POST\">\n"+
-		"<input type=\"hidden\" name=\"action\" value=\"main\">\n"+
-		"<input type=\"submit\" value=\"main\">\n"+
-		"</form>\n")
-	fmt.Fprintf(w, "</td><td>\n")
-	fmt.Fprintf(w, "<form action=\"%s\" method=\"POST\">\n"+
-		"<input type=\"hidden\" name=\"action\" value=\"back\">\n"+
-		"<input type=\"hidden\" name=\"md5\" value=\"%s\">\n"+
-		"<input type=\"hidden\" name=\"msg\" value=\"%s\">\n"+
-		"<input type=\"submit\" value=\"show other side\">\n"+
-		"<input type=\"submit\" value=\"skip\">\n"+
-		"</form>\n", url, card.Md5, msg)
-	fmt.Fprintf(w, "</td>\n")
-	fmt.Fprintf(w, "<td><form><label>%s</label></form></td>\n", msg)
-	fmt.Fprintf(w, "</tr></table>\n")
-	cardHtml(w, card.Front)
-	fmt.Fprintf(w, "</body></html>\n")
+// When typeAnswer is true, the page has a text box to type the answer in.
+func (h *httpHandler) pageCardFront(w http.ResponseWriter, rs *requestState, url string, cardSetId string, card *gocards.Card, msg string, typeAnswer bool) {
+	page := newCardPage(url, cardSetId, card, msg, false)
+	page.TypeAnswer = typeAnswer
+	h.render(rs, "front", page)
+}
+
+// pageCardBack displays the back of a card.
+// The time the front of the card was shown is passed along so the time spent answering can be logged.
+// When a grade is suggested for a typed answer, a diff of the typed answer and the answer is displayed.
+// Typed text that is not in the answer is struck out in red.
+// Text in the answer that was not typed is underlined in green.
+// The suggested grade button has focus so pressing enter grades the card with it.
+func (h *httpHandler) pageCardBack(w http.ResponseWriter, rs *requestState, url string, cardSetId string, card *gocards.Card, grades []string, shown string, msg string, answer string, suggested string) {
+	page := newCardPage(url, cardSetId, card, msg, true)
+	page.Shown = shown
+	page.Grades = grades
+	page.Suggested = suggested
+	if suggested != "" {
+		page.Diff = gocards.DiffAnswer(answer, card.Answer())
+	}
+	h.render(rs, "back", page)
+}
+
+// pageQuiz displays the front of a card and buttons for the possible answers.
+// Each button posts the md5 of the card the answer is from.
+func (h *httpHandler) pageQuiz(w http.ResponseWriter, rs *requestState, url string, cardSetId string, card *gocards.Card, options []*gocards.Card, msg string) {
+	page := newCardPage(url, cardSetId, card, msg, false)
+	page.Options = options
+	h.render(rs, "quiz", page)
+}
+
+// pageQuizResult displays whether the answer picked in a quiz was right and the back of the card.
+func (h *httpHandler) pageQuizResult(w http.ResponseWriter, rs *requestState, url string, cardSetId string, card *gocards.Card, right bool, msg string) {
+	page := newCardPage(url, cardSetId, card, msg, true)
+	page.Right = right
+	h.render(rs, "quizResult", page)
+}
+
+// pageMessage displays a webpage with a message on it.
+func (h *httpHandler) pageMessage(w http.ResponseWriter, rs *requestState, msg string) {
+	h.render(rs, "message", &cardPage{Msg: msg})
 }
 
 // pageError displays the error.
-func pageError(w http.ResponseWriter, err error) {
-	pageMessage(w, err.Error())
+func (h *httpHandler) pageError(w http.ResponseWriter, rs *requestState, err error) {
+	h.pageMessage(w, rs, err.Error())
+}
+
+// cardHtml turns a card side into html.
+func cardHtml(card string) template.HTML {
+	if strings.HasPrefix(card, "image:") {
+		return template.HTML(image(card[len("image:"):]))
+	} else if strings.HasPrefix(card, "images:") {
+		return template.HTML(images(card[len("images:"):]))
+	} else if strings.HasPrefix(card, "wikipedia:") {
+		return template.HTML(wikipediaImages(card[len("wikipedia:"):]))
+	}
+	return template.HTML(markdownToHTML(card))
+}
+
+// clozeMarkdown highlights the cloze deletion hidden by a cloze card.
+// On the front of the card the deletion is hidden, on the back it is shown.
+// Sides of cards that are not cloze cards are returned unchanged.
+func clozeMarkdown(card *gocards.Card, side string, back bool) string {
+	if card.Cloze == 0 {
+		return side
+	}
+	return gocards.Cloze(side, card.Cloze, func(answer, hint string) string {
+		if back {
+			return "<mark>" + answer + "</mark>"
+		} else if hint != "" {
+			return "<mark>[" + hint + "]</mark>"
+		}
+		return "<mark>[...]</mark>"
+	})
 }
 
 // wikipediaImages gets the images on a wikipedia page.
@@ -763,12 +1860,232 @@ func mainClean(o *options) error {
 	return nil
 }
 
-// mainHttp serves webpages.
+// mainForecast prints the number of cards due each day for each card set and for all card sets.
+// The number of days is set with --days and defaults to 7.
+// The first day is today and includes cards that are overdue.
+func mainForecast(o *options) error {
+	days := 7
+	if o.s["days"] != "" {
+		var err error
+		days, err = strconv.Atoi(o.s["days"])
+		if err != nil || days < 1 {
+			return errors.New("--days must be a number more than 0")
+		}
+	}
+	cardSets, err := loadCardSets(o, o.s["profile"])
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	forecasts, total := gocards.ForecastCardSets(cardSets, days, now)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "card set\t")
+	for i := 0; i < days; i++ {
+		fmt.Fprintf(tw, "%s\t", now.AddDate(0, 0, i).Format("01-02"))
+	}
+	fmt.Fprintf(tw, "\n")
+	row := func(id string, counts []int) {
+		fmt.Fprintf(tw, "%s\t", id)
+		for _, count := range counts {
+			fmt.Fprintf(tw, "%d\t", count)
+		}
+		fmt.Fprintf(tw, "\n")
+	}
+	for _, cardSet := range cardSets {
+		row(cardSet.Id, forecasts[cardSet.Id])
+	}
+	row("total", total)
+	return tw.Flush()
+}
+
+// mainPasswd sets the password of a user in the "passwords" file in the root path.
+// The user is added to the file if it is not in it.
+// The password is read from standard input.
+func mainPasswd(o *options) error {
+	if o.s["user"] == "" {
+		return errors.New("--user must be specified")
+	}
+	filePath := filepath.Join(o.s["path"], "passwords")
+	passwords, err := gocards.LoadPasswords(filePath)
+	if err != nil {
+		return err
+	}
+	fmt.Print("Password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	err = passwords.Set(o.s["user"], strings.TrimRight(line, "\r\n"))
+	if err != nil {
+		return err
+	}
+	err = passwords.Save(filePath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Password set for %s\n", o.s["user"])
+	return nil
+}
+
+// mainHttp runs the web server.
+// The server listens on localhost port 8080 unless the "addr" or "port" options are set.
+// The server uses https when the "tls" option is set or a certificate and key are passed in.
+// A self signed certificate is made in the root path when no certificate and key are passed in.
+// When the "autosave" option is more than 0, data is saved every that many seconds.
+// On SIGINT or SIGTERM, the server waits for requests being served to finish and then saves data before exiting.
 func mainHttp(o *options) error {
 	httpHandler, err := newHttpHandler(o)
 	if err != nil {
 		return err
 	}
-	http.Handle("/", httpHandler)
-	return http.ListenAndServe(":8080", nil)
+	addr, err := listenAddr(o)
+	if err != nil {
+		return err
+	}
+	certFile, keyFile, err := tlsFiles(o, addr)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if httpHandler.autosave > 0 {
+		go httpHandler.autosaveLoop(ctx)
+	}
+	server := &http.Server{Addr: addr, Handler: httpHandler}
+	serverErr := make(chan error, 1)
+	go func() {
+		if certFile != "" {
+			fmt.Printf("Listening on https://%s\n", addr)
+			serverErr <- server.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			fmt.Printf("Listening on http://%s\n", addr)
+			serverErr <- server.ListenAndServe()
+		}
+	}()
+	select {
+	case err = <-serverErr:
+		return err
+	case <-ctx.Done():
+	}
+	// a second signal exits right away
+	stop()
+	fmt.Println("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = server.Shutdown(shutdownCtx)
+	httpHandler.mutex.Lock()
+	defer httpHandler.mutex.Unlock()
+	saveErr := httpHandler.saveProfiles()
+	if saveErr != nil {
+		return saveErr
+	}
+	return err
+}
+
+// listenAddr returns the address for the web server to listen on from the "addr" and "port" options.
+// The address defaults to localhost so the server can't be reached from other machines.
+// Use an "addr" of "0.0.0.0" to listen on all interfaces.
+func listenAddr(o *options) (string, error) {
+	host := o.s["addr"]
+	if host == "" {
+		host = "localhost"
+	}
+	port := o.s["port"]
+	if port == "" {
+		port = "8080"
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil || p < 1 || p > 65535 {
+		return "", errors.New("Port must be a number between 1 and 65535")
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// tlsFiles returns the certificate and key files for the web server to use.
+// Empty strings are returned if the server should not use https.
+// An error is returned if one occurs.
+func tlsFiles(o *options, addr string) (string, string, error) {
+	certFile, keyFile := o.s["cert"], o.s["key"]
+	if (certFile == "") != (keyFile == "") {
+		return "", "", errors.New("The cert and key options must be used together")
+	}
+	if certFile != "" {
+		return certFile, keyFile, nil
+	}
+	if !o.b["tls"] {
+		return "", "", nil
+	}
+	certFile = filepath.Join(o.s["path"], "tlsCert.pem")
+	keyFile = filepath.Join(o.s["path"], "tlsKey.pem")
+	_, certErr := os.Stat(certFile)
+	_, keyErr := os.Stat(keyFile)
+	if certErr == nil && keyErr == nil {
+		return certFile, keyFile, nil
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", "", err
+	}
+	err = selfSignedCert(certFile, keyFile, host)
+	if err != nil {
+		return "", "", err
+	}
+	fmt.Printf("Made self signed certificate %s\n", certFile)
+	return certFile, keyFile, nil
+}
+
+// selfSignedCert writes a self signed certificate and its key to the files passed in.
+// The certificate is for localhost, the host passed in and the addresses of this machine.
+// This lets browsers on other machines on the network connect after accepting the certificate.
+func selfSignedCert(certFile, keyFile, host string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
+	if err != nil {
+		return err
+	}
+	serialNumber, err := cryptorand.Int(cryptorand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{Organization: []string{"gocards"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+	}
+	hosts := []string{host}
+	addrs, err := net.InterfaceAddrs()
+	if err == nil {
+		for _, a := range addrs {
+			if ipNet, ok := a.(*net.IPNet); ok {
+				hosts = append(hosts, ipNet.IP.String())
+			}
+		}
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			if !ip.IsUnspecified() {
+				template.IPAddresses = append(template.IPAddresses, ip)
+			}
+		} else if h != "" && h != "localhost" {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(cryptorand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
 }
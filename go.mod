@@ -5,3 +5,5 @@ go 1.21
 require github.com/gomarkdown/markdown v0.0.0-20230716120725-531d2d74bc12
 
 require golang.org/x/net v0.14.0
+
+require golang.org/x/crypto v0.14.0
@@ -0,0 +1,162 @@
+package gocards
+
+import (
+	"strings"
+	"unicode"
+)
+
+// accents maps accented letters to the letters they are compared as in typed answers.
+var accents = map[rune]rune{
+	'à': 'a', 'á': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a', 'å': 'a', 'ā': 'a', 'ă': 'a', 'ą': 'a',
+	'ç': 'c', 'ć': 'c', 'ĉ': 'c', 'č': 'c',
+	'ď': 'd', 'đ': 'd',
+	'è': 'e', 'é': 'e', 'ê': 'e', 'ë': 'e', 'ē': 'e', 'ė': 'e', 'ę': 'e', 'ě': 'e',
+	'ĝ': 'g', 'ğ': 'g',
+	'ĥ': 'h',
+	'ì': 'i', 'í': 'i', 'î': 'i', 'ï': 'i', 'ī': 'i', 'į': 'i', 'ı': 'i',
+	'ĵ': 'j',
+	'ł': 'l', 'ľ': 'l',
+	'ñ': 'n', 'ń': 'n', 'ň': 'n',
+	'ò': 'o', 'ó': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o', 'ø': 'o', 'ō': 'o', 'ő': 'o',
+	'ř': 'r',
+	'ś': 's', 'ŝ': 's', 'ş': 's', 'š': 's',
+	'ť': 't', 'ţ': 't',
+	'ù': 'u', 'ú': 'u', 'û': 'u', 'ü': 'u', 'ū': 'u', 'ŭ': 'u', 'ů': 'u', 'ű': 'u',
+	'ý': 'y', 'ÿ': 'y',
+	'ź': 'z', 'ż': 'z', 'ž': 'z',
+}
+
+// foldRune returns the rune a rune is compared as in typed answers.
+// Case and accents are ignored.
+func foldRune(r rune) rune {
+	r = unicode.ToLower(r)
+	if folded, ok := accents[r]; ok {
+		return folded
+	}
+	return r
+}
+
+// NormalizeAnswer returns a typed answer as it is compared to the answer of a card.
+// Case, accents and punctuation are ignored and runs of white space are the same as a single space.
+func NormalizeAnswer(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsPunct(r) {
+			continue
+		}
+		b.WriteRune(foldRune(r))
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// Answer returns the text a typed answer for the card is compared to.
+// This is the back of the card or the hidden text of a cloze card.
+func (card *Card) Answer() string {
+	if card.Cloze != 0 {
+		return ClozeAnswer(card.Front, card.Cloze)
+	}
+	return card.Back
+}
+
+// SuggestGrade returns the grade suggested for a typed answer.
+// Correct answers are "good", answers that are mostly correct are "hard" and other answers are "again".
+func SuggestGrade(typed, answer string) Grade {
+	typed, answer = NormalizeAnswer(typed), NormalizeAnswer(answer)
+	if typed == "" {
+		return Again
+	} else if typed == answer {
+		return Good
+	}
+	if similarity(typed, answer) >= 0.8 {
+		return Hard
+	}
+	return Again
+}
+
+// similarity returns how alike two strings are from 0 for nothing in common to 1 for the same.
+func similarity(s1, s2 string) float64 {
+	a, b := []rune(s1), []rune(s2)
+	if len(a)+len(b) == 0 {
+		return 1
+	}
+	common := 0
+	for _, part := range diff(a, b) {
+		if part.Kind == DiffSame {
+			common += len([]rune(part.Text))
+		}
+	}
+	return float64(2*common) / float64(len(a)+len(b))
+}
+
+type DiffKind int
+
+const (
+	// text in both the typed answer and the answer
+	DiffSame DiffKind = iota
+	// text in the typed answer that is not in the answer
+	DiffExtra
+	// text in the answer that is missing from the typed answer
+	DiffMissing
+)
+
+// DiffPart is a piece of a diff between a typed answer and an answer.
+type DiffPart struct {
+	Text string
+	Kind DiffKind
+}
+
+// DiffAnswer returns a character level diff between a typed answer and an answer.
+// Characters are compared ignoring case and accents.
+func DiffAnswer(typed, answer string) []DiffPart {
+	return diff([]rune(typed), []rune(answer))
+}
+
+// diff returns the diff of two lists of runes using their longest common subsequence.
+func diff(a, b []rune) []DiffPart {
+	// lengths[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if foldRune(a[i]) == foldRune(b[j]) {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	parts := []DiffPart{}
+	add := func(r rune, kind DiffKind) {
+		if len(parts) > 0 && parts[len(parts)-1].Kind == kind {
+			parts[len(parts)-1].Text += string(r)
+		} else {
+			parts = append(parts, DiffPart{string(r), kind})
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if foldRune(a[i]) == foldRune(b[j]) {
+			// show the answer so the right case and accents are seen
+			add(b[j], DiffSame)
+			i, j = i+1, j+1
+		} else if lengths[i+1][j] >= lengths[i][j+1] {
+			add(a[i], DiffExtra)
+			i += 1
+		} else {
+			add(b[j], DiffMissing)
+			j += 1
+		}
+	}
+	for ; i < len(a); i++ {
+		add(a[i], DiffExtra)
+	}
+	for ; j < len(b); j++ {
+		add(b[j], DiffMissing)
+	}
+	return parts
+}
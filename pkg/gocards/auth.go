@@ -0,0 +1,195 @@
+package gocards
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// number of PBKDF2 iterations used when hashing a new password
+const PasswordIterations = 100000
+
+// dummyHash is checked for unknown users so logging in takes as long as it does for users that exist
+var dummyHash = fmt.Sprintf("pbkdf2-sha256$%d$%s$%s", PasswordIterations, strings.Repeat("00", 16), strings.Repeat("00", sha256.Size))
+
+// user and profile names are letters, numbers, dots, dashes and underscores
+var nameRegexp = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// ValidUser returns true if the user name can be used.
+// "." and ".." can't be used because each user has a profile with their name.
+func ValidUser(user string) bool {
+	return nameRegexp.MatchString(user) && user != "." && user != ".."
+}
+
+// Passwords holds password hashes by user name.
+type Passwords map[string]string
+
+// LoadPasswords reads the password file.
+// Each line of the file is a user name and a password hash.
+// An empty Passwords is returned if the file does not exist.
+func LoadPasswords(filePath string) (Passwords, error) {
+	passwords := Passwords{}
+	if _, err := os.Stat(filePath); errors.Is(err, os.ErrNotExist) {
+		return passwords, nil
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		data := strings.Split(scanner.Text(), " | ")
+		if len(data) != 2 || !ValidUser(data[0]) {
+			return nil, errors.New("Invalid line found in passwords")
+		}
+		passwords[data[0]] = data[1]
+	}
+	return passwords, scanner.Err()
+}
+
+// Save writes the password file.
+// The file can only be read by its owner.
+func (p Passwords) Save(filePath string) error {
+	users := []string{}
+	for user := range p {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	for _, user := range users {
+		_, err = file.WriteString(fmt.Sprintf("%s | %s\n", user, p[user]))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Set hashes the password and stores it for the user.
+func (p Passwords) Set(user, password string) error {
+	if !ValidUser(user) {
+		return errors.New("User names can only have letters, numbers, dots, dashes and underscores")
+	}
+	if password == "" {
+		return errors.New("Password can't be empty")
+	}
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	p[user] = hash
+	return nil
+}
+
+// Check returns true if the password is right for the user.
+// A password is hashed even for an unknown user so the time taken does not show which users exist.
+func (p Passwords) Check(user, password string) bool {
+	hash, ok := p[user]
+	if !ok {
+		CheckPassword(password, dummyHash)
+		return false
+	}
+	return CheckPassword(password, hash)
+}
+
+// HashPassword returns a hash of the password made with PBKDF2 and a random salt.
+// The hash is "pbkdf2-sha256$<iterations>$<salt>$<key>" with the salt and key in hex.
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, 16)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", err
+	}
+	key := pbkdf2.Key([]byte(password), salt, PasswordIterations, sha256.Size, sha256.New)
+	return fmt.Sprintf("pbkdf2-sha256$%d$%s$%s", PasswordIterations, hex.EncodeToString(salt), hex.EncodeToString(key)), nil
+}
+
+// CheckPassword returns true if the password matches the hash made by HashPassword.
+func CheckPassword(password, hash string) bool {
+	data := strings.Split(hash, "$")
+	if len(data) != 4 || data[0] != "pbkdf2-sha256" {
+		return false
+	}
+	iterations, err := strconv.Atoi(data[1])
+	if err != nil || iterations < 1 {
+		return false
+	}
+	salt, err := hex.DecodeString(data[2])
+	if err != nil {
+		return false
+	}
+	key, err := hex.DecodeString(data[3])
+	if err != nil || len(key) == 0 {
+		return false
+	}
+	return hmac.Equal(key, pbkdf2.Key([]byte(password), salt, iterations, len(key), sha256.New))
+}
+
+// LoadAuthKey returns the key used to sign cookies from the file.
+// A new random key is made and written to the file if the file does not exist.
+func LoadAuthKey(filePath string) ([]byte, error) {
+	if _, err := os.Stat(filePath); errors.Is(err, os.ErrNotExist) {
+		key := make([]byte, 32)
+		_, err = rand.Read(key)
+		if err != nil {
+			return nil, err
+		}
+		err = os.WriteFile(filePath, []byte(hex.EncodeToString(key)+"\n"), 0600)
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) < 16 {
+		return nil, errors.New("Invalid auth key file")
+	}
+	return key, nil
+}
+
+// Sign returns the value with an HMAC-SHA256 signature made with the key added to it.
+func Sign(key []byte, value string) string {
+	return value + "|" + Signature(key, value)
+}
+
+// Verify returns the value from a signed value made by Sign.
+// Returns false if the signature is not right.
+func Verify(key []byte, signed string) (string, bool) {
+	i := strings.LastIndex(signed, "|")
+	if i < 0 {
+		return "", false
+	}
+	value := signed[:i]
+	if !hmac.Equal([]byte(signed[i+1:]), []byte(Signature(key, value))) {
+		return "", false
+	}
+	return value, true
+}
+
+// Signature returns the HMAC-SHA256 of the value made with the key in hex.
+func Signature(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
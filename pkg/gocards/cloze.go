@@ -0,0 +1,73 @@
+package gocards
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// a cloze deletion is {{c1::text}} or {{c1::text::hint}}
+var clozeRegexp = regexp.MustCompile(`\{\{c(\d+)::(.*?)(?:::(.*?))?\}\}`)
+
+// ClozeNumbers returns the numbers of the cloze deletions in the text in order.
+// Each number is returned once even if more than one deletion has that number.
+func ClozeNumbers(text string) []int {
+	found := map[int]bool{}
+	numbers := []int{}
+	for _, m := range clozeRegexp.FindAllStringSubmatch(text, -1) {
+		number, err := strconv.Atoi(m[1])
+		if err != nil || number == 0 || found[number] {
+			continue
+		}
+		found[number] = true
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	return numbers
+}
+
+// Cloze replaces the cloze deletions in the text.
+// Deletions with the number passed in are replaced by what the replace function returns.
+// All other deletions are replaced by their text.
+// Numbers are compared as numbers, so {{c01::text}} is deletion 1, and there is no deletion 0.
+func Cloze(text string, number int, replace func(answer, hint string) string) string {
+	return clozeRegexp.ReplaceAllStringFunc(text, func(s string) string {
+		m := clozeRegexp.FindStringSubmatch(s)
+		n, err := strconv.Atoi(m[1])
+		if err == nil && n != 0 && n == number {
+			return replace(m[2], m[3])
+		}
+		return m[2]
+	})
+}
+
+// ClozeAnswer returns the text of the deletions with the number passed in.
+func ClozeAnswer(text string, number int) string {
+	answers := []string{}
+	Cloze(text, number, func(answer, hint string) string {
+		answers = append(answers, answer)
+		return ""
+	})
+	return strings.Join(answers, ", ")
+}
+
+// NewClozeCards returns a card for each cloze deletion number in the front of the card passed in.
+// The front and back of each card are the front of the card passed in with the deletions still marked.
+// Any back of the card passed in is added to the back of each card.
+func NewClozeCards(card *Card) []*Card {
+	cards := []*Card{}
+	for _, number := range ClozeNumbers(card.Front) {
+		back := card.Front
+		if card.Back != "" {
+			back += "\n\n" + card.Back
+		}
+		cloze := NewCard(fmt.Sprintf("%s::c%d", card.Id, number), true, card.Front, back)
+		cloze.SourceId = card.SourceId
+		cloze.Cloze = number
+		cloze.Tags = card.Tags
+		cards = append(cards, cloze)
+	}
+	return cards
+}
@@ -0,0 +1,140 @@
+package gocards
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// CardSetConfig holds the settings from the header of a card file.
+// The header is optional and is between two "---" lines at the start of the file.
+// Each line in the header is a "name: value" pair.
+// A card is a leech once it has lapsed LeechThreshold times, 0 turns off leech detection.
+// When LeechAction is "suspend", leeches are suspended.
+// When Reverse is true, every card with two sides also gets a reverse card.
+type CardSetConfig struct {
+	Scheduler      string
+	Retention      float64
+	Lapse          Lapse
+	Intervals      []int
+	NewPerDay      int
+	ReviewsPerDay  int
+	Grading        string
+	LeechThreshold int
+	LeechAction    string
+	Reverse        bool
+}
+
+const DefaultLeechThreshold = 8
+
+func NewCardSetConfig() *CardSetConfig {
+	return &CardSetConfig{LeechThreshold: DefaultLeechThreshold}
+}
+
+// NewScheduler returns the scheduler named in the config.
+func (c *CardSetConfig) NewScheduler() (Scheduler, error) {
+	if c.Scheduler == "" || c.Scheduler == "fibonacci" {
+		s := NewFibonacciScheduler()
+		s.Lapse = c.Lapse
+		if c.Intervals != nil {
+			s.Intervals = c.Intervals
+		}
+		return s, nil
+	} else if c.Scheduler == "sm2" {
+		return NewSM2Scheduler(c.Lapse), nil
+	} else if c.Scheduler == "fsrs" {
+		return NewFSRSScheduler(c.Retention), nil
+	}
+	return nil, errors.New("Unknown scheduler")
+}
+
+// set sets a config value from a line in a card file header.
+func (c *CardSetConfig) set(line string) error {
+	name, value, found := strings.Cut(line, ":")
+	if !found {
+		return errors.New("Invalid header line")
+	}
+	name, value = trim(name), trim(value)
+	if name == "scheduler" {
+		c.Scheduler = value
+		_, err := c.NewScheduler()
+		if err != nil {
+			return err
+		}
+	} else if name == "retention" {
+		retention, err := strconv.ParseFloat(value, 64)
+		if err != nil || retention <= 0 || retention >= 1 {
+			return errors.New("Retention must be a number between 0 and 1")
+		}
+		c.Retention = retention
+	} else if name == "lapse" {
+		lapse, err := ParseLapse(value)
+		if err != nil {
+			return err
+		}
+		c.Lapse = lapse
+	} else if name == "intervals" {
+		intervals, err := parseIntervals(value)
+		if err != nil {
+			return err
+		}
+		c.Intervals = intervals
+	} else if name == "new per day" {
+		newPerDay, err := strconv.Atoi(value)
+		if err != nil || newPerDay < 0 {
+			return errors.New("New per day must be a number that is not negative")
+		}
+		c.NewPerDay = newPerDay
+	} else if name == "reviews per day" {
+		reviewsPerDay, err := strconv.Atoi(value)
+		if err != nil || reviewsPerDay < 0 {
+			return errors.New("Reviews per day must be a number that is not negative")
+		}
+		c.ReviewsPerDay = reviewsPerDay
+	} else if name == "grading" {
+		if value != "four" && value != "two" {
+			return errors.New("Grading must be \"four\" or \"two\"")
+		}
+		c.Grading = value
+	} else if name == "leech threshold" {
+		leechThreshold, err := strconv.Atoi(value)
+		if err != nil || leechThreshold < 0 {
+			return errors.New("Leech threshold must be a number that is not negative")
+		}
+		c.LeechThreshold = leechThreshold
+	} else if name == "leech action" {
+		if value != "flag" && value != "suspend" {
+			return errors.New("Leech action must be \"flag\" or \"suspend\"")
+		}
+		c.LeechAction = value
+	} else if name == "reverse" {
+		reverse, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("Reverse must be \"true\" or \"false\"")
+		}
+		c.Reverse = reverse
+	} else {
+		return errors.New("Unknown header setting")
+	}
+	return nil
+}
+
+// parseIntervals parses a list of intervals in days separated by spaces.
+// The list must start with 0, the interval for new cards, and never go down.
+func parseIntervals(s string) ([]int, error) {
+	intervals := []int{}
+	for _, field := range strings.Fields(s) {
+		interval, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, errors.New("Intervals must be numbers")
+		}
+		if len(intervals) > 0 && interval < intervals[len(intervals)-1] {
+			return nil, errors.New("Intervals must not go down")
+		}
+		intervals = append(intervals, interval)
+	}
+	if len(intervals) < 2 || intervals[0] != 0 {
+		return nil, errors.New("Intervals must start with 0 and have at least one more interval")
+	}
+	return intervals, nil
+}
@@ -0,0 +1,33 @@
+package gocards
+
+import (
+	"path"
+	"strings"
+)
+
+// Folders returns the folders the card set is in from the top folder down.
+// Card sets at the top of the root path are not in any folders.
+func (cs *CardSet) Folders() []string {
+	folders := []string{}
+	dir := path.Dir(cs.Id)
+	for dir != "." && dir != "/" {
+		folders = append([]string{dir}, folders...)
+		dir = path.Dir(dir)
+	}
+	return folders
+}
+
+// InFolder returns true if the card set is in the folder or a folder under it.
+func (cs *CardSet) InFolder(folder string) bool {
+	return strings.HasPrefix(cs.Id, strings.TrimSuffix(folder, "/")+"/")
+}
+
+func GetFolderCardSets(cardSets []*CardSet, folder string) []*CardSet {
+	foundCardSets := []*CardSet{}
+	for _, cs := range cardSets {
+		if cs.InFolder(folder) {
+			foundCardSets = append(foundCardSets, cs)
+		}
+	}
+	return foundCardSets
+}
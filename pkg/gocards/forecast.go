@@ -0,0 +1,66 @@
+package gocards
+
+import (
+	"math"
+	"time"
+)
+
+// DueTime returns the time the card is due.
+// The zero time is returned for cards with an interval of 0, which are never due.
+func (card *Card) DueTime() time.Time {
+	interval := card.Interval()
+	if interval == 0 {
+		return time.Time{}
+	}
+	return card.LastReviewTime.Add(time.Duration(interval) * 24 * time.Hour)
+}
+
+// Forecast returns the number of cards that are due on each of the next days.
+// The first day is the day of the time passed in and includes cards that are overdue.
+// Blank cards, suspended cards, cards not in the card file and cards with an interval of 0 are not counted.
+// Buried cards are counted on the day they come back if they are due by then.
+func Forecast(cards []*Card, days int, now time.Time) []int {
+	counts := make([]int, days)
+	for _, card := range cards {
+		if !card.InCardFile || card.Blank() || card.Suspended {
+			continue
+		}
+		due := card.DueTime()
+		if due.IsZero() {
+			continue
+		}
+		if card.Buried(now) && due.Before(card.BuriedUntil) {
+			due = card.BuriedUntil
+		}
+		i := DaysBetween(now, due)
+		if i < 0 {
+			i = 0
+		}
+		if i < days {
+			counts[i] += 1
+		}
+	}
+	return counts
+}
+
+// ForecastCardSets returns the forecast for each card set, keyed by card set id, and the total for all card sets.
+func ForecastCardSets(cardSets []*CardSet, days int, now time.Time) (map[string][]int, []int) {
+	forecasts := map[string][]int{}
+	total := make([]int, days)
+	for _, cs := range cardSets {
+		forecast := Forecast(cs.Cards, days, now)
+		for i, count := range forecast {
+			total[i] += count
+		}
+		forecasts[cs.Id] = forecast
+	}
+	return forecasts, total
+}
+
+// DaysBetween returns the number of calendar days from the first time to the second time.
+// The number is negative if the second time is on an earlier day.
+func DaysBetween(from, to time.Time) int {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.Local)
+	return int(math.Round(to.Sub(from).Hours() / 24))
+}
@@ -0,0 +1,130 @@
+package gocards
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	fsrsDecay            = -0.5
+	fsrsFactor           = 19.0 / 81.0
+	fsrsDefaultRetention = 0.9
+	fsrsMaximumInterval  = 36500
+)
+
+// default weights from version 4.5 of FSRS
+var fsrsWeights = [...]float64{0.4872, 1.4003, 3.7145, 13.8206, 5.1618, 1.2298, 0.8975, 0.031, 1.6474, 0.1367, 1.0461, 2.1072, 0.0793, 0.3246, 1.587, 0.2272, 2.8755}
+
+// FSRSScheduler schedules cards using the Free Spaced Repetition Scheduler algorithm.
+// Each card has a stability, the number of days until the chance of remembering it drops to 90%,
+// and a difficulty between 1 and 10.
+// Cards are scheduled for when the chance of remembering them drops to the target retention.
+type FSRSScheduler struct {
+	Retention float64
+	Weights   [17]float64
+}
+
+func NewFSRSScheduler(retention float64) *FSRSScheduler {
+	if retention == 0 {
+		retention = fsrsDefaultRetention
+	}
+	return &FSRSScheduler{retention, fsrsWeights}
+}
+
+func (s *FSRSScheduler) Interval(card *Card) int {
+	return scheduledInterval(card)
+}
+
+func (s *FSRSScheduler) Buckets() []int {
+	return Intervals[:]
+}
+
+func (s *FSRSScheduler) Due(card *Card, now time.Time) (bool, int) {
+	interval := s.Interval(card)
+	return dueAfter(card, interval, now), interval
+}
+
+func (s *FSRSScheduler) Review(card *Card, g Grade, now time.Time) {
+	// grade on the 1 to 4 scale used by FSRS
+	grade := int(g)
+
+	interval := s.Interval(card)
+	if card.Stability == 0 && interval == 0 {
+		card.Stability = s.Weights[grade-1]
+		card.Difficulty = s.initialDifficulty(grade)
+	} else {
+		if card.Stability == 0 {
+			// cards done before the card set used FSRS start with a stability of their interval
+			card.Stability = float64(interval)
+			card.Difficulty = s.initialDifficulty(3)
+		}
+		elapsed := now.Sub(card.LastReviewTime).Hours() / 24
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		r := s.retrievability(elapsed, card.Stability)
+		if grade == 1 {
+			card.Stability = s.lapseStability(card.Difficulty, card.Stability, r)
+		} else {
+			card.Stability = s.recallStability(card.Difficulty, card.Stability, r, grade)
+		}
+		card.Difficulty = s.nextDifficulty(card.Difficulty, grade)
+	}
+
+	if grade == 1 {
+		card.CorrectCount = 0
+		card.ScheduledDays = 0
+	} else {
+		card.CorrectCount += 1
+		card.ScheduledDays = s.nextInterval(card.Stability)
+	}
+	card.LastReviewTime = now
+}
+
+// retrievability returns the chance of remembering a card elapsed days after it was done.
+func (s *FSRSScheduler) retrievability(elapsed, stability float64) float64 {
+	return math.Pow(1+fsrsFactor*elapsed/stability, fsrsDecay)
+}
+
+// nextInterval returns the number of days until the chance of remembering drops to the target retention.
+func (s *FSRSScheduler) nextInterval(stability float64) int {
+	interval := stability / fsrsFactor * (math.Pow(s.Retention, 1/fsrsDecay) - 1)
+	return int(math.Min(math.Max(math.Round(interval), 1), fsrsMaximumInterval))
+}
+
+func (s *FSRSScheduler) initialDifficulty(grade int) float64 {
+	return clampDifficulty(s.Weights[4] - float64(grade-3)*s.Weights[5])
+}
+
+func (s *FSRSScheduler) nextDifficulty(difficulty float64, grade int) float64 {
+	d := difficulty - s.Weights[6]*float64(grade-3)
+	// mean reversion towards the difficulty of a new card answered with "good"
+	return clampDifficulty(s.Weights[7]*s.initialDifficulty(3) + (1-s.Weights[7])*d)
+}
+
+func (s *FSRSScheduler) recallStability(difficulty, stability, r float64, grade int) float64 {
+	hardPenalty, easyBonus := 1.0, 1.0
+	if grade == 2 {
+		hardPenalty = s.Weights[15]
+	} else if grade == 4 {
+		easyBonus = s.Weights[16]
+	}
+	return stability * (1 + math.Exp(s.Weights[8])*
+		(11-difficulty)*
+		math.Pow(stability, -s.Weights[9])*
+		(math.Exp((1-r)*s.Weights[10])-1)*
+		hardPenalty*
+		easyBonus)
+}
+
+func (s *FSRSScheduler) lapseStability(difficulty, stability, r float64) float64 {
+	lapse := s.Weights[11] *
+		math.Pow(difficulty, -s.Weights[12]) *
+		(math.Pow(stability+1, s.Weights[13]) - 1) *
+		math.Exp((1-r)*s.Weights[14])
+	return math.Min(lapse, stability)
+}
+
+func clampDifficulty(difficulty float64) float64 {
+	return math.Min(math.Max(difficulty, 1), 10)
+}
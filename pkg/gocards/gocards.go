@@ -16,57 +16,175 @@ import (
 var Intervals = [...]int{0, 0, 0, 1, 1, 2, 3, 5, 8, 13, 21, 34, 55, 89, 144, 233, 377}
 var IntervalValues = len(Intervals) - 3
 
+// Card is one card from a card file.
+// SourceId is the id of the card in the card file the card was made from.
+// It is the same as Id except for cards made from another card, like reverse cards.
+// Cards made from the same card are siblings.
+// Cloze is the number of the cloze deletion hidden by the card, 0 if the card is not a cloze card.
+// Tags are the tags at the end of the card's line in the card file.
 type Card struct {
 	Md5            string
 	Id             string
+	SourceId       string
+	Cloze          int
+	Tags           []string
 	InCardFile     bool
 	Front          string
 	Back           string
 	LastReviewTime time.Time
 	CorrectCount   int
+	LastGrade      Grade
+	Lapses         int
+	Suspended      bool
+	BuriedUntil    time.Time
+	Ease           float64
+	ScheduledDays  int
+	Stability      float64
+	Difficulty     float64
+	Scheduler      Scheduler
 }
 
 func NewCard(id string, inCardFile bool, front string, back string) *Card {
 	md5 := fmt.Sprintf("%x", md5.Sum([]byte(id)))
-	return &Card{Md5: md5, Id: id, InCardFile: true, Front: front, Back: back}
+	return &Card{Md5: md5, Id: id, SourceId: id, InCardFile: true, Front: front, Back: back}
 }
 
 func NewCardStats(id string, lastReviewTime time.Time, correctCount int) *Card {
 	md5 := fmt.Sprintf("%x", md5.Sum([]byte(id)))
-	return &Card{Md5: md5, Id: id, InCardFile: false, CorrectCount: correctCount, LastReviewTime: lastReviewTime}
+	return &Card{Md5: md5, Id: id, SourceId: id, InCardFile: false, CorrectCount: correctCount, LastReviewTime: lastReviewTime}
+}
+
+// ReverseSuffix is added to the id of a card to make the id of its reverse card.
+const ReverseSuffix = "::reverse"
+
+// ReverseMarker at the start of a card line makes a reverse card for that card.
+const ReverseMarker = "<-> "
+
+// NewReverseCard returns a card with the front and back of the card passed in swapped.
+func NewReverseCard(card *Card) *Card {
+	reverse := NewCard(card.Id+ReverseSuffix, true, card.Back, card.Front)
+	reverse.SourceId = card.SourceId
+	reverse.Tags = card.Tags
+	return reverse
 }
 
 func (card *Card) Blank() bool {
 	return card.Front == "" || card.Back == ""
 }
 
-func (card *Card) Due() (bool, int) {
-	now := time.Now()
-	interval := card.Interval()
-	if interval > 0 {
-		elapsed := now.Sub(card.LastReviewTime)
-		if elapsed.Hours() < float64(interval)*24 {
-			return false, interval
+// dataFields returns the optional values written after the correct count in a card data line.
+// Values that are not set are not written so data files stay the same for cards that do not use them.
+func (card *Card) dataFields() []string {
+	fields := []string{}
+	if card.LastGrade != 0 {
+		fields = append(fields, "grade="+card.LastGrade.String())
+	}
+	if card.Lapses != 0 {
+		fields = append(fields, fmt.Sprintf("lapses=%d", card.Lapses))
+	}
+	if card.Suspended {
+		fields = append(fields, "suspended=true")
+	}
+	if card.Buried(time.Now()) {
+		buriedUntil, err := card.BuriedUntil.MarshalText()
+		if err == nil {
+			fields = append(fields, "buried="+string(buriedUntil))
 		}
+	}
+	if card.Ease != 0 {
+		fields = append(fields, fmt.Sprintf("ease=%.2f", card.Ease))
+	}
+	if card.ScheduledDays != 0 {
+		fields = append(fields, fmt.Sprintf("days=%d", card.ScheduledDays))
+	}
+	if card.Stability != 0 {
+		fields = append(fields, fmt.Sprintf("stability=%.4f", card.Stability))
+	}
+	if card.Difficulty != 0 {
+		fields = append(fields, fmt.Sprintf("difficulty=%.4f", card.Difficulty))
+	}
+	return fields
+}
+
+// setDataField sets an optional value read from a card data line.
+func (card *Card) setDataField(field string) error {
+	name, value, found := strings.Cut(field, "=")
+	if !found {
+		return errors.New("Invalid field found in card data")
+	}
+	var err error
+	if name == "grade" {
+		card.LastGrade, err = ParseGrade(value)
+	} else if name == "lapses" {
+		card.Lapses, err = strconv.Atoi(value)
+	} else if name == "suspended" {
+		card.Suspended, err = strconv.ParseBool(value)
+	} else if name == "buried" {
+		err = card.BuriedUntil.UnmarshalText([]byte(value))
+	} else if name == "ease" {
+		card.Ease, err = strconv.ParseFloat(value, 64)
+	} else if name == "days" {
+		card.ScheduledDays, err = strconv.Atoi(value)
+	} else if name == "stability" {
+		card.Stability, err = strconv.ParseFloat(value, 64)
+	} else if name == "difficulty" {
+		card.Difficulty, err = strconv.ParseFloat(value, 64)
 	} else {
-		return false, interval
+		return errors.New("Unknown field found in card data")
 	}
+	return err
+}
 
-	return true, interval
+// Bury hides the card until the start of the next day.
+func (card *Card) Bury(now time.Time) {
+	card.BuriedUntil = time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+}
+
+func (card *Card) Buried(now time.Time) bool {
+	return now.Before(card.BuriedUntil)
+}
+
+// Hidden returns true if the card is suspended or buried.
+// Hidden cards are not done in spaced repetition.
+func (card *Card) Hidden(now time.Time) bool {
+	return card.Suspended || card.Buried(now)
+}
+
+func (card *Card) Due() (bool, int) {
+	return card.scheduler().Due(card, time.Now())
 }
 
 func (card *Card) Interval() int {
-	index := card.CorrectCount
-	if card.CorrectCount > len(Intervals) {
-		index = len(Intervals) - 1
+	return card.scheduler().Interval(card)
+}
+
+func (card *Card) Review(grade Grade, now time.Time) {
+	// only cards that had been learned can lapse
+	if grade == Again && card.Interval() > 0 {
+		card.Lapses += 1
+	}
+	card.scheduler().Review(card, grade, now)
+	card.LastGrade = grade
+}
+
+// IntervalBucket returns the interval of the card grouped by the intervals its scheduler displays.
+func (card *Card) IntervalBucket() int {
+	return IntervalBucket(card.scheduler().Buckets(), card.Interval())
+}
+
+func (card *Card) scheduler() Scheduler {
+	if card.Scheduler == nil {
+		return DefaultScheduler
 	}
-	return Intervals[index]
+	return card.Scheduler
 }
 
 func GetDueCards(cards []*Card) []*Card {
+	now := time.Now()
+	siblings := siblingsDone(cards, now)
 	foundCards := []*Card{}
 	for _, card := range cards {
-		if card.Blank() {
+		if card.Blank() || card.Hidden(now) || siblings[card.Id] {
 			continue
 		}
 		due, _ := card.Due()
@@ -78,9 +196,11 @@ func GetDueCards(cards []*Card) []*Card {
 }
 
 func GetDueOrNewCards(cards []*Card) []*Card {
+	now := time.Now()
+	siblings := siblingsDone(cards, now)
 	foundCards := []*Card{}
 	for _, card := range cards {
-		if card.Blank() {
+		if card.Blank() || card.Hidden(now) || siblings[card.Id] {
 			continue
 		}
 		due, interval := card.Due()
@@ -93,12 +213,48 @@ func GetDueOrNewCards(cards []*Card) []*Card {
 }
 
 func GetIntervalCards(cards []*Card, interval int) []*Card {
+	now := time.Now()
+	siblings := siblingsDone(cards, now)
 	foundCards := []*Card{}
 	for _, card := range cards {
-		if card.Blank() {
+		if card.Blank() || card.Hidden(now) || siblings[card.Id] {
 			continue
 		}
-		if interval == card.Interval() {
+		if interval == card.IntervalBucket() {
+			foundCards = append(foundCards, card)
+		}
+	}
+	return foundCards
+}
+
+// siblingsDone returns the ids of cards that have a sibling that was done today.
+// Siblings are not done on the same day so one side of a card does not give away the other.
+func siblingsDone(cards []*Card, now time.Time) map[string]bool {
+	doneToday := map[string]string{}
+	for _, card := range cards {
+		if day(card.LastReviewTime) == day(now) {
+			if _, ok := doneToday[card.SourceId]; !ok {
+				doneToday[card.SourceId] = card.Id
+			} else {
+				// more than one sibling done today
+				doneToday[card.SourceId] = ""
+			}
+		}
+	}
+	siblings := map[string]bool{}
+	for _, card := range cards {
+		id, ok := doneToday[card.SourceId]
+		if ok && id != card.Id {
+			siblings[card.Id] = true
+		}
+	}
+	return siblings
+}
+
+func GetSuspendedCards(cards []*Card) []*Card {
+	foundCards := []*Card{}
+	for _, card := range cards {
+		if card.InCardFile && card.Suspended {
 			foundCards = append(foundCards, card)
 		}
 	}
@@ -109,16 +265,27 @@ type CardSet struct {
 	Id           string
 	CardFilePath string
 	CardDataPath string
+	CardLogPath  string
 	Cards        []*Card
+	Config       *CardSetConfig
+	Scheduler    Scheduler
+	Quota        *DailyQuota
+	reviews      []*ReviewEntry
 }
 
+// the review log for a card set is next to its data file, ".cdd" becomes ".cdl"
 func NewCardSet(id, cardFilePath, cardDataPath string) *CardSet {
-	return &CardSet{id, cardFilePath, cardDataPath, nil}
+	cardLogPath := strings.TrimSuffix(cardDataPath, "d") + "l"
+	return &CardSet{id, cardFilePath, cardDataPath, cardLogPath, nil, NewCardSetConfig(), DefaultScheduler, NewDailyQuota(0, 0), nil}
 }
 
 func (cs *CardSet) Load() error {
 	var err error
-	cs.Cards, err = LoadCards(cs.CardFilePath)
+	cs.Cards, cs.Config, err = LoadCards(cs.CardFilePath)
+	if err != nil {
+		return err
+	}
+	cs.Scheduler, err = cs.Config.NewScheduler()
 	if err != nil {
 		return err
 	}
@@ -126,14 +293,105 @@ func (cs *CardSet) Load() error {
 	if err != nil {
 		return err
 	}
+	for _, card := range cs.Cards {
+		card.Scheduler = cs.Scheduler
+	}
 	return nil
 }
 
+// SaveData writes the card data file and adds reviews done since the last save to the review log.
 func (cs *CardSet) SaveData(clean bool) error {
-	return SaveCardData(cs.CardDataPath, cs.Cards, clean)
+	err := SaveCardData(cs.CardDataPath, cs.Cards, clean)
+	if err != nil {
+		return err
+	}
+	err = AppendReviewLog(cs.CardLogPath, cs.reviews)
+	if err != nil {
+		return err
+	}
+	cs.reviews = nil
+	return nil
+}
+
+// Review updates the card after it has been done and counts it in the daily quota.
+// The review is kept to be added to the review log when the card set data is saved.
+// The duration is the time spent answering the card.
+func (cs *CardSet) Review(card *Card, grade Grade, now time.Time, duration time.Duration) {
+	isNew := card.LastReviewTime.IsZero()
+	previousInterval := card.Interval()
+	isReview := !isNew && previousInterval > 0
+	cs.Quota.Add(cs.Id, isNew, isReview, now)
+	card.Review(grade, now)
+	if grade == Again && cs.Leech(card) && cs.Config.LeechAction == "suspend" {
+		card.Suspended = true
+	}
+	cs.reviews = append(cs.reviews, NewReviewEntry(now, card.Id, grade, previousInterval, card.Interval(), duration))
+}
+
+// Leech returns true if the card has lapsed as many times as the leech threshold of the card set.
+func (cs *CardSet) Leech(card *Card) bool {
+	return cs.Config.LeechThreshold > 0 && card.Lapses >= cs.Config.LeechThreshold
+}
+
+// Leeches returns the cards in the card file that are leeches.
+func (cs *CardSet) Leeches() []*Card {
+	leeches := []*Card{}
+	for _, card := range cs.Cards {
+		if card.InCardFile && cs.Leech(card) {
+			leeches = append(leeches, card)
+		}
+	}
+	return leeches
+}
+
+// ReviewLog returns all the reviews of cards in the card set.
+// This includes reviews that have not been saved yet.
+func (cs *CardSet) ReviewLog() ([]*ReviewEntry, error) {
+	entries, err := LoadReviewLog(cs.CardLogPath)
+	if err != nil {
+		return nil, err
+	}
+	return append(entries, cs.reviews...), nil
+}
+
+// NewLeft returns the number of new cards left to do today in the card set.
+// Both the limit for the card set and the limit for all card sets are used.
+// Returns -1 if there is no limit.
+func (cs *CardSet) NewLeft(now time.Time) int {
+	return minLeft(left(cs.Config.NewPerDay, cs.Quota.New(cs.Id, now)), cs.Quota.NewLeft(now))
+}
+
+// ReviewsLeft returns the number of reviews left to do today in the card set.
+// Both the limit for the card set and the limit for all card sets are used.
+// Returns -1 if there is no limit.
+func (cs *CardSet) ReviewsLeft(now time.Time) int {
+	return minLeft(left(cs.Config.ReviewsPerDay, cs.Quota.Reviews(cs.Id, now)), cs.Quota.ReviewsLeft(now))
+}
+
+// LimitCards removes cards from the slice passed in once the limits for new cards and reviews today have been reached.
+// Cards being learned are never removed.
+func (cs *CardSet) LimitCards(cards []*Card, now time.Time) []*Card {
+	newLeft, reviewsLeft := cs.NewLeft(now), cs.ReviewsLeft(now)
+	limited := []*Card{}
+	for _, card := range cards {
+		if card.LastReviewTime.IsZero() {
+			if newLeft == 0 {
+				continue
+			}
+			newLeft -= 1
+		} else if card.Interval() > 0 {
+			if reviewsLeft == 0 {
+				continue
+			}
+			reviewsLeft -= 1
+		}
+		limited = append(limited, card)
+	}
+	return limited
 }
 
 func (cs *CardSet) Stats() *CardSetStats {
+	now := time.Now()
 	stats := NewCardSetStats(cs.Id)
 	for _, card := range cs.Cards {
 		stats.TotalCount += 1
@@ -147,14 +405,28 @@ func (cs *CardSet) Stats() *CardSetStats {
 			stats.BlankCount += 1
 			continue
 		}
+		if cs.Leech(card) {
+			stats.LeechCount += 1
+		}
+		if card.Suspended {
+			stats.SuspendedCount += 1
+			continue
+		}
+		buried := card.Buried(now)
+		if buried {
+			stats.BuriedCount += 1
+		}
 		due, interval := card.Due()
-		_, ok := stats.IntervalCount[interval]
+		bucket := IntervalBucket(card.scheduler().Buckets(), interval)
+		_, ok := stats.IntervalCount[bucket]
 		if ok {
-			stats.IntervalCount[interval] += 1
+			stats.IntervalCount[bucket] += 1
 		} else {
-			stats.IntervalCount[interval] = 1
+			stats.IntervalCount[bucket] = 1
 		}
-		if interval == 0 {
+		if buried {
+			continue
+		} else if interval == 0 {
 			stats.NewCount += 1
 		} else if due {
 			stats.DueCount += 1
@@ -300,20 +572,45 @@ func LoadCardSets(cardSets []*CardSet) error {
 }
 
 type CardSetStats struct {
-	Id            string
-	IntervalCount map[int]int
-	TotalCount    int
-	BlankCount    int
-	CardCount     int
-	DueCount      int
-	NewCount      int
-	OldCount      int
+	Id             string
+	IntervalCount  map[int]int
+	TotalCount     int
+	BlankCount     int
+	CardCount      int
+	DueCount       int
+	NewCount       int
+	OldCount       int
+	LeechCount     int
+	SuspendedCount int
+	BuriedCount    int
 }
 
 func NewCardSetStats(id string) *CardSetStats {
 	return &CardSetStats{Id: id, IntervalCount: make(map[int]int)}
 }
 
+// Add adds the counts from other stats to these stats.
+// This is used to get stats for more than one card set.
+func (stats *CardSetStats) Add(other *CardSetStats) {
+	for interval, count := range other.IntervalCount {
+		stats.IntervalCount[interval] += count
+	}
+	stats.TotalCount += other.TotalCount
+	stats.BlankCount += other.BlankCount
+	stats.CardCount += other.CardCount
+	stats.DueCount += other.DueCount
+	stats.NewCount += other.NewCount
+	stats.OldCount += other.OldCount
+	stats.LeechCount += other.LeechCount
+	stats.SuspendedCount += other.SuspendedCount
+	stats.BuriedCount += other.BuriedCount
+}
+
+// day returns the calendar day of the time passed in.
+func day(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
 func trim(s string) string {
 	return strings.Trim(s, " \t")
 }
@@ -324,6 +621,7 @@ const (
 	frontMultiCode
 	backMulti
 	backMultiCode
+	header
 )
 
 // returns id, front, parseState
@@ -394,15 +692,17 @@ func errorWithLineNumber(err error, lineNumber int) error {
 	return errors.New(err.Error() + " on line " + strconv.Itoa(lineNumber))
 }
 
-func LoadCards(filePath string) ([]*Card, error) {
+func LoadCards(filePath string) ([]*Card, *CardSetConfig, error) {
 	var err error
 
 	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer file.Close()
 
+	config := NewCardSetConfig()
+
 	fronts := make(map[string]bool)
 	cards := make([]*Card, 0, 10)
 	addCard := func(id, front, back string) error {
@@ -420,6 +720,7 @@ func LoadCards(filePath string) ([]*Card, error) {
 
 	var id, front, back string
 	var parseState int
+	reverse := map[string]bool{}
 
 	lineNumber := 0
 	scanner := bufio.NewScanner(file)
@@ -427,28 +728,53 @@ func LoadCards(filePath string) ([]*Card, error) {
 		line := scanner.Text()
 		lineNumber += 1
 
-		if parseState == newCard {
+		if lineNumber == 1 && line == "---" {
+			parseState = header
+		} else if parseState == header {
+			if line == "---" {
+				parseState = newCard
+			} else if len(line) > 0 && !strings.HasPrefix(line, "#") {
+				err = config.set(line)
+				if err != nil {
+					return nil, nil, errorWithLineNumber(err, lineNumber)
+				}
+			}
+		} else if parseState == newCard {
 			if len(line) > 0 && !strings.HasPrefix(line, "#") {
+				reverseLine := strings.HasPrefix(line, ReverseMarker)
+				line = strings.TrimPrefix(line, ReverseMarker)
 				sides := strings.Split(line, " | ")
+				tags := []string{}
+				if len(sides) == 3 {
+					tags, err = parseTags(sides[2])
+					if err != nil {
+						return nil, nil, errorWithLineNumber(err, lineNumber)
+					}
+					sides = sides[:2]
+				}
 				if len(sides) == 1 {
 					id, front, parseState = parseOneSide(sides[0])
 					err = addCard(id, front, "")
 					if err != nil {
-						return nil, errorWithLineNumber(err, lineNumber)
+						return nil, nil, errorWithLineNumber(err, lineNumber)
 					}
 				} else if len(sides) == 2 {
 					id, front, back, parseState = parseTwoSides(sides[0], sides[1])
 					err = addCard(id, front, back)
 					if err != nil {
-						return nil, errorWithLineNumber(err, lineNumber)
+						return nil, nil, errorWithLineNumber(err, lineNumber)
 					}
 				} else {
-					return nil, errorWithLineNumber(errors.New("Unexpected number of sides"), lineNumber)
+					return nil, nil, errorWithLineNumber(errors.New("Unexpected number of sides"), lineNumber)
+				}
+				cards[len(cards)-1].Tags = tags
+				if reverseLine {
+					reverse[trim(id)] = true
 				}
 			}
 		} else if parseState == frontMulti {
 			if len(cards) == 0 {
-				return nil, errorWithLineNumber(errors.New("Unexpected number of card"), lineNumber)
+				return nil, nil, errorWithLineNumber(errors.New("Unexpected number of card"), lineNumber)
 			} else if line == "` | `" {
 				parseState = backMulti
 			} else if line == "` | ```" {
@@ -465,7 +791,7 @@ func LoadCards(filePath string) ([]*Card, error) {
 			}
 		} else if parseState == frontMultiCode {
 			if len(cards) == 0 {
-				return nil, errorWithLineNumber(errors.New("Unexpected number of card"), lineNumber)
+				return nil, nil, errorWithLineNumber(errors.New("Unexpected number of card"), lineNumber)
 			} else if line == "``` | `" {
 				parseState = backMulti
 				cards[len(cards)-1].Front += "\n```"
@@ -483,7 +809,7 @@ func LoadCards(filePath string) ([]*Card, error) {
 			}
 		} else if parseState == backMulti {
 			if len(cards) == 0 {
-				return nil, errorWithLineNumber(errors.New("Unexpected number of card"), lineNumber)
+				return nil, nil, errorWithLineNumber(errors.New("Unexpected number of card"), lineNumber)
 			} else if line == "`" {
 				parseState = newCard
 			} else if cards[len(cards)-1].Back == "" {
@@ -493,7 +819,7 @@ func LoadCards(filePath string) ([]*Card, error) {
 			}
 		} else if parseState == backMultiCode {
 			if len(cards) == 0 {
-				return nil, errorWithLineNumber(errors.New("Unexpected number of card"), lineNumber)
+				return nil, nil, errorWithLineNumber(errors.New("Unexpected number of card"), lineNumber)
 			} else if line == "```" {
 				parseState = newCard
 				cards[len(cards)-1].Back += "\n```"
@@ -501,19 +827,54 @@ func LoadCards(filePath string) ([]*Card, error) {
 				cards[len(cards)-1].Back += "\n" + line
 			}
 		} else {
-			return nil, errorWithLineNumber(err, lineNumber)
+			return nil, nil, errorWithLineNumber(err, lineNumber)
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, errorWithLineNumber(err, lineNumber)
+		return nil, nil, errorWithLineNumber(err, lineNumber)
 	}
 
 	if parseState != newCard {
-		return nil, errorWithLineNumber(errors.New("Invalid parse state"), lineNumber)
+		return nil, nil, errorWithLineNumber(errors.New("Invalid parse state"), lineNumber)
 	}
 
-	return cards, nil
+	cards, err = expandClozes(cards, fronts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, card := range cards {
+		if (config.Reverse || reverse[card.Id]) && !card.Blank() && card.Cloze == 0 {
+			if _, exists := fronts[card.Id+ReverseSuffix]; exists {
+				return nil, nil, errors.New(fmt.Sprintf("Duplicate card id for reverse of card %s", card.Id))
+			}
+			fronts[card.Id+ReverseSuffix] = true
+			cards = append(cards, NewReverseCard(card))
+		}
+	}
+
+	return cards, config, nil
+}
+
+// expandClozes replaces cards with cloze deletions with a card for each deletion.
+func expandClozes(cards []*Card, ids map[string]bool) ([]*Card, error) {
+	expanded := make([]*Card, 0, len(cards))
+	for _, card := range cards {
+		clozes := NewClozeCards(card)
+		if len(clozes) == 0 {
+			expanded = append(expanded, card)
+			continue
+		}
+		for _, cloze := range clozes {
+			if _, exists := ids[cloze.Id]; exists {
+				return nil, errors.New(fmt.Sprintf("Duplicate card id for cloze of card %s", card.Id))
+			}
+			ids[cloze.Id] = true
+		}
+		expanded = append(expanded, clozes...)
+	}
+	return expanded, nil
 }
 
 // the key for the cards map returned is the file path for each card set
@@ -534,7 +895,7 @@ func LoadCardData(filePath string, cards []*Card) ([]*Card, error) {
 	for scanner.Scan() {
 		line := scanner.Text()
 		data := strings.Split(line, " | ")
-		if len(data) != 3 {
+		if len(data) < 3 {
 			return nil, errors.New("Invalid line found in card data")
 		}
 
@@ -549,18 +910,26 @@ func LoadCardData(filePath string, cards []*Card) ([]*Card, error) {
 			return nil, err
 		}
 
-		found := false
+		var found *Card
 		for _, card := range cards {
 			if card.Id == id {
-				found = true
-				card.CorrectCount = correctCount
-				card.LastReviewTime = lastReviewTime
+				found = card
+				found.CorrectCount = correctCount
+				found.LastReviewTime = lastReviewTime
 				break
 			}
 		}
 
-		if !found {
-			cards = append(cards, NewCardStats(id, lastReviewTime, correctCount))
+		if found == nil {
+			found = NewCardStats(id, lastReviewTime, correctCount)
+			cards = append(cards, found)
+		}
+
+		for _, field := range data[3:] {
+			err = found.setDataField(field)
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -572,7 +941,7 @@ func LoadCardData(filePath string, cards []*Card) ([]*Card, error) {
 }
 
 func LoadCardsAndData(cardsFilepath string) ([]*Card, error) {
-	cards, err := LoadCards(cardsFilepath)
+	cards, _, err := LoadCards(cardsFilepath)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("Unable to load card set %s: %s", cardsFilepath, err))
 	}
@@ -602,7 +971,8 @@ func SaveCardData(filePath string, cards []*Card, clean bool) error {
 			return err
 		}
 
-		line := fmt.Sprintf("%s | %s | %d\n", card.Id, lastReviewTime, card.CorrectCount)
+		fields := append([]string{card.Id, string(lastReviewTime), strconv.Itoa(card.CorrectCount)}, card.dataFields()...)
+		line := strings.Join(fields, " | ") + "\n"
 		_, err = file.WriteString(line)
 		if err != nil {
 			return err
@@ -0,0 +1,119 @@
+package gocards
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReviewEntry is one review of a card in a review log.
+// Review logs are only ever appended to so they merge well in source control.
+// Each line in a review log is:
+// time | card id | grade | previous interval | new interval | seconds spent answering
+type ReviewEntry struct {
+	Time             time.Time
+	Id               string
+	Grade            Grade
+	PreviousInterval int
+	NewInterval      int
+	Duration         time.Duration
+}
+
+func NewReviewEntry(now time.Time, id string, grade Grade, previousInterval, newInterval int, duration time.Duration) *ReviewEntry {
+	return &ReviewEntry{now, id, grade, previousInterval, newInterval, duration}
+}
+
+func (e *ReviewEntry) line() (string, error) {
+	t, err := e.Time.MarshalText()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s | %s | %s | %d | %d | %.1f\n", t, e.Id, e.Grade, e.PreviousInterval, e.NewInterval, e.Duration.Seconds()), nil
+}
+
+func parseReviewEntry(line string) (*ReviewEntry, error) {
+	data := strings.Split(line, " | ")
+	if len(data) != 6 {
+		return nil, errors.New("Invalid line found in review log")
+	}
+	var t time.Time
+	err := t.UnmarshalText([]byte(data[0]))
+	if err != nil {
+		return nil, err
+	}
+	grade, err := ParseGrade(data[2])
+	if err != nil {
+		return nil, err
+	}
+	previousInterval, err := strconv.Atoi(data[3])
+	if err != nil {
+		return nil, err
+	}
+	newInterval, err := strconv.Atoi(data[4])
+	if err != nil {
+		return nil, err
+	}
+	seconds, err := strconv.ParseFloat(data[5], 64)
+	if err != nil {
+		return nil, err
+	}
+	duration := time.Duration(seconds * float64(time.Second))
+	return NewReviewEntry(t, data[1], grade, previousInterval, newInterval, duration), nil
+}
+
+// LoadReviewLog reads all the reviews in a review log.
+// An empty slice is returned if the review log does not exist.
+func LoadReviewLog(filePath string) ([]*ReviewEntry, error) {
+	entries := []*ReviewEntry{}
+	if _, err := os.Stat(filePath); errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	lineNumber := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineNumber += 1
+		entry, err := parseReviewEntry(scanner.Text())
+		if err != nil {
+			return nil, errorWithLineNumber(err, lineNumber)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// AppendReviewLog adds reviews to the end of a review log.
+// The review log is created if it does not exist.
+func AppendReviewLog(filePath string, entries []*ReviewEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	for _, entry := range entries {
+		line, err := entry.line()
+		if err != nil {
+			return err
+		}
+		_, err = file.WriteString(line)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
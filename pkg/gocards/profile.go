@@ -0,0 +1,71 @@
+package gocards
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ProfilesDir is the directory in the root path with the data files of each profile.
+const ProfilesDir = "profiles"
+
+// ValidProfile returns true if the profile name can be used.
+// The default profile has an empty name.
+func ValidProfile(profile string) bool {
+	return profile == "" || (nameRegexp.MatchString(profile) && profile != "." && profile != "..")
+}
+
+// ProfilePath returns the directory the data files of a profile are in.
+// The data files of the default profile are in the root path.
+func ProfilePath(rootPath, profile string) string {
+	if profile == "" {
+		return rootPath
+	}
+	return filepath.Join(rootPath, ProfilesDir, profile)
+}
+
+// UseProfile changes the data file and review log paths of the card sets to paths in the directory of the profile.
+// Each data file has the same path in the profile directory as it has in the root path.
+// The card files are not changed, so all profiles share them.
+func UseProfile(rootPath, profile string, cardSets []*CardSet) error {
+	if !ValidProfile(profile) {
+		return errors.New("Profile names can only have letters, numbers, dots, dashes and underscores")
+	}
+	if profile == "" {
+		return nil
+	}
+	profilePath := ProfilePath(rootPath, profile)
+	for _, cs := range cardSets {
+		relPath, err := filepath.Rel(rootPath, cs.CardDataPath)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(relPath, "..") {
+			return errors.New("Data file not in root path: " + cs.CardDataPath)
+		}
+		cs.CardDataPath = filepath.Join(profilePath, relPath)
+		cs.CardLogPath = strings.TrimSuffix(cs.CardDataPath, "d") + "l"
+	}
+	return nil
+}
+
+// Profiles returns the names of the profiles in the root path sorted by name.
+// The default profile is not returned.
+func Profiles(rootPath string) ([]string, error) {
+	profiles := []string{}
+	entries, err := os.ReadDir(filepath.Join(rootPath, ProfilesDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return profiles, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && ValidProfile(entry.Name()) {
+			profiles = append(profiles, entry.Name())
+		}
+	}
+	sort.Strings(profiles)
+	return profiles, nil
+}
@@ -0,0 +1,34 @@
+package gocards
+
+import (
+	"sort"
+)
+
+// Distractors returns up to n cards to use as wrong answers when quizzing the card passed in.
+// The cards are picked from the cards passed in and have answers that are the most like the answer of the card.
+// Each card returned has a different answer that is not the same as the answer of the card.
+func Distractors(card *Card, cards []*Card, n int) []*Card {
+	answer := NormalizeAnswer(card.Answer())
+	answers := map[string]bool{answer: true}
+	candidates := []*Card{}
+	scores := map[*Card]float64{}
+	for _, c := range cards {
+		if !c.InCardFile || c.Blank() {
+			continue
+		}
+		a := NormalizeAnswer(c.Answer())
+		if answers[a] {
+			continue
+		}
+		answers[a] = true
+		candidates = append(candidates, c)
+		scores[c] = similarity(answer, a)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return scores[candidates[i]] > scores[candidates[j]]
+	})
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	return candidates
+}
@@ -0,0 +1,178 @@
+package gocards
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DailyQuota counts the cards done today in each card set.
+// Cards that have never been done before are counted as new cards.
+// Cards that had an interval of more than 0 days are counted as reviews.
+// NewPerDay and ReviewsPerDay limit the cards done in all card sets each day, 0 means no limit.
+type DailyQuota struct {
+	NewPerDay     int
+	ReviewsPerDay int
+	day           string
+	newCounts     map[string]int
+	reviewCounts  map[string]int
+}
+
+func NewDailyQuota(newPerDay, reviewsPerDay int) *DailyQuota {
+	return &DailyQuota{newPerDay, reviewsPerDay, "", map[string]int{}, map[string]int{}}
+}
+
+// reset starts counting again when the day changes.
+func (q *DailyQuota) reset(now time.Time) {
+	if q.day != day(now) {
+		q.day = day(now)
+		q.newCounts = map[string]int{}
+		q.reviewCounts = map[string]int{}
+	}
+}
+
+// Add counts a card done in the card set.
+func (q *DailyQuota) Add(id string, isNew, isReview bool, now time.Time) {
+	q.reset(now)
+	if isNew {
+		q.newCounts[id] += 1
+	} else if isReview {
+		q.reviewCounts[id] += 1
+	}
+}
+
+// New returns the number of new cards done today in the card set.
+func (q *DailyQuota) New(id string, now time.Time) int {
+	q.reset(now)
+	return q.newCounts[id]
+}
+
+// Reviews returns the number of reviews done today in the card set.
+func (q *DailyQuota) Reviews(id string, now time.Time) int {
+	q.reset(now)
+	return q.reviewCounts[id]
+}
+
+// TotalNew returns the number of new cards done today in all card sets.
+func (q *DailyQuota) TotalNew(now time.Time) int {
+	q.reset(now)
+	return sum(q.newCounts)
+}
+
+// TotalReviews returns the number of reviews done today in all card sets.
+func (q *DailyQuota) TotalReviews(now time.Time) int {
+	q.reset(now)
+	return sum(q.reviewCounts)
+}
+
+// NewLeft returns the number of new cards left to do today in all card sets.
+// Returns -1 if there is no limit.
+func (q *DailyQuota) NewLeft(now time.Time) int {
+	return left(q.NewPerDay, q.TotalNew(now))
+}
+
+// ReviewsLeft returns the number of reviews left to do today in all card sets.
+// Returns -1 if there is no limit.
+func (q *DailyQuota) ReviewsLeft(now time.Time) int {
+	return left(q.ReviewsPerDay, q.TotalReviews(now))
+}
+
+// Load reads the counts for today from the file.
+// Counts from other days are ignored.
+func (q *DailyQuota) Load(filePath string, now time.Time) error {
+	q.reset(now)
+	if _, err := os.Stat(filePath); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		data := strings.Split(scanner.Text(), " | ")
+		if len(data) != 4 {
+			return errors.New("Invalid line found in daily counts")
+		}
+		if data[0] != q.day {
+			continue
+		}
+		newCount, err := strconv.Atoi(data[2])
+		if err != nil {
+			return err
+		}
+		reviewCount, err := strconv.Atoi(data[3])
+		if err != nil {
+			return err
+		}
+		q.newCounts[data[1]] = newCount
+		q.reviewCounts[data[1]] = reviewCount
+	}
+	return scanner.Err()
+}
+
+// Save writes the counts for today to the file.
+func (q *DailyQuota) Save(filePath string, now time.Time) error {
+	q.reset(now)
+	ids := []string{}
+	for id := range q.newCounts {
+		ids = append(ids, id)
+	}
+	for id := range q.reviewCounts {
+		if _, ok := q.newCounts[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	for _, id := range ids {
+		line := fmt.Sprintf("%s | %s | %d | %d\n", q.day, id, q.newCounts[id], q.reviewCounts[id])
+		_, err = file.WriteString(line)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// left returns how many more can be done before the limit is reached.
+// Returns -1 if there is no limit.
+func left(limit, count int) int {
+	if limit == 0 {
+		return -1
+	}
+	if count >= limit {
+		return 0
+	}
+	return limit - count
+}
+
+// minLeft returns the smaller of two values returned by left.
+func minLeft(a, b int) int {
+	if a < 0 {
+		return b
+	}
+	if b < 0 || a < b {
+		return a
+	}
+	return b
+}
+
+func sum(counts map[string]int) int {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	return total
+}
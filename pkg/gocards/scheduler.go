@@ -0,0 +1,191 @@
+package gocards
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Grade is how well a card was remembered when it was done.
+type Grade int
+
+const (
+	Again Grade = iota + 1
+	Hard
+	Good
+	Easy
+)
+
+var gradeNames = map[Grade]string{Again: "again", Hard: "hard", Good: "good", Easy: "easy"}
+
+var Grades = []Grade{Again, Hard, Good, Easy}
+
+func (g Grade) String() string {
+	return gradeNames[g]
+}
+
+func ParseGrade(s string) (Grade, error) {
+	for grade, name := range gradeNames {
+		if s == name {
+			return grade, nil
+		}
+	}
+	// names used by card sets graded with two buttons
+	if s == "correct" {
+		return Good, nil
+	} else if s == "incorrect" {
+		return Again, nil
+	}
+	return 0, errors.New("Invalid grade")
+}
+
+// Scheduler decides when a card should be done again.
+// Cards use DefaultScheduler unless a scheduler has been set on them.
+type Scheduler interface {
+	// Interval returns the number of days between reviews for the card.
+	// An interval of 0 means the card is new or being learned.
+	Interval(card *Card) int
+	// Due returns true if the card is due at the time passed in.
+	// The interval of the card is also returned.
+	Due(card *Card, now time.Time) (bool, int)
+	// Review updates the card after it has been done.
+	Review(card *Card, grade Grade, now time.Time)
+	// Buckets returns the intervals cards are grouped by when they are displayed.
+	Buckets() []int
+}
+
+var DefaultScheduler Scheduler = NewFibonacciScheduler()
+
+// Lapse is how far back a card goes in its schedule when it is graded "again".
+// The zero value sends the card back to the start of its schedule.
+// When Steps is set, the card goes back that many steps.
+// When Fraction is set, the card goes back to that fraction of its interval.
+type Lapse struct {
+	Steps    int
+	Fraction float64
+}
+
+func ParseLapse(s string) (Lapse, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 1 && fields[0] == "reset" {
+		return Lapse{}, nil
+	} else if len(fields) == 2 && fields[0] == "steps" {
+		steps, err := strconv.Atoi(fields[1])
+		if err == nil && steps > 0 {
+			return Lapse{Steps: steps}, nil
+		}
+	} else if len(fields) == 2 && fields[0] == "fraction" {
+		fraction, err := strconv.ParseFloat(fields[1], 64)
+		if err == nil && fraction > 0 && fraction < 1 {
+			return Lapse{Fraction: fraction}, nil
+		}
+	}
+	return Lapse{}, errors.New("Lapse must be \"reset\", \"steps <number>\" or \"fraction <number between 0 and 1>\"")
+}
+
+// FibonacciScheduler schedules cards using a fixed list of intervals.
+// Each "good" review moves a card one step along the list.
+// A "hard" review keeps a card at the same step once it is past the first steps of the list,
+// unless the card was also graded "hard" the last time it was done, so hard cards move along the list more slowly.
+// An "easy" review moves a card two steps along the list.
+// An "again" review moves the card back in the list as set by Lapse.
+type FibonacciScheduler struct {
+	Intervals []int
+	Lapse     Lapse
+}
+
+func NewFibonacciScheduler() *FibonacciScheduler {
+	return &FibonacciScheduler{Intervals[:], Lapse{}}
+}
+
+func (s *FibonacciScheduler) Interval(card *Card) int {
+	index := card.CorrectCount
+	if index >= len(s.Intervals) {
+		index = len(s.Intervals) - 1
+	}
+	return s.Intervals[index]
+}
+
+func (s *FibonacciScheduler) Buckets() []int {
+	return s.Intervals
+}
+
+func (s *FibonacciScheduler) Due(card *Card, now time.Time) (bool, int) {
+	interval := s.Interval(card)
+	return dueAfter(card, interval, now), interval
+}
+
+func (s *FibonacciScheduler) Review(card *Card, grade Grade, now time.Time) {
+	learning := s.Interval(card) == 0
+	card.LastReviewTime = now
+	if grade == Again {
+		s.lapse(card)
+	} else if grade == Hard {
+		// card.LastGrade is still the grade of the review before this one
+		if learning || card.LastGrade == Hard {
+			card.CorrectCount += 1
+		}
+	} else if grade == Easy {
+		if learning {
+			// skip the rest of the steps with an interval of 0
+			for s.Interval(card) == 0 && card.CorrectCount < len(s.Intervals)-1 {
+				card.CorrectCount += 1
+			}
+		} else {
+			card.CorrectCount += 2
+		}
+	} else {
+		card.CorrectCount += 1
+	}
+}
+
+func (s *FibonacciScheduler) lapse(card *Card) {
+	if card.CorrectCount >= len(s.Intervals) {
+		card.CorrectCount = len(s.Intervals) - 1
+	}
+	if s.Lapse.Steps > 0 {
+		card.CorrectCount -= s.Lapse.Steps
+		if card.CorrectCount < 0 {
+			card.CorrectCount = 0
+		}
+	} else if s.Lapse.Fraction > 0 {
+		target := s.Lapse.Fraction * float64(s.Interval(card))
+		for card.CorrectCount > 0 && float64(s.Interval(card)) > target {
+			card.CorrectCount -= 1
+		}
+	} else {
+		card.CorrectCount = 0
+	}
+}
+
+// scheduledInterval returns the interval stored in the card by schedulers that don't use Intervals.
+// Cards done before their card set used one of these schedulers keep their place in the default intervals.
+func scheduledInterval(card *Card) int {
+	if card.ScheduledDays == 0 && card.CorrectCount > 0 {
+		return NewFibonacciScheduler().Interval(card)
+	}
+	return card.ScheduledDays
+}
+
+// dueAfter returns true if interval days have passed since the card was last reviewed.
+// Cards with an interval of 0 are never due.
+func dueAfter(card *Card, interval int, now time.Time) bool {
+	if interval <= 0 {
+		return false
+	}
+	elapsed := now.Sub(card.LastReviewTime)
+	return elapsed.Hours() >= float64(interval)*24
+}
+
+// IntervalBucket returns the largest value in intervals that is not more than the interval passed in.
+// This groups intervals from schedulers that don't use a list of intervals.
+func IntervalBucket(intervals []int, interval int) int {
+	bucket := 0
+	for _, i := range intervals {
+		if i <= interval {
+			bucket = i
+		}
+	}
+	return bucket
+}
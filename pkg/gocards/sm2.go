@@ -0,0 +1,122 @@
+package gocards
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	sm2InitialEase = 2.5
+	sm2MinimumEase = 1.3
+	// a "hard" review multiplies the interval by this instead of the ease factor
+	sm2HardInterval = 1.2
+	// an "easy" review multiplies the interval by this as well as the ease factor
+	sm2EasyBonus = 1.3
+	// the interval after a new card is first graded "easy"
+	sm2EasyFirstInterval = 4
+)
+
+// SM2Scheduler schedules cards using the SuperMemo 2 algorithm.
+// Each card has an ease factor that changes with every review.
+// The interval of a card grows by its ease factor each time it is graded "good".
+// A "hard" grade grows the interval by a smaller fixed amount and an "easy" grade grows it by more than the ease factor.
+// When a card is graded "again" it goes back in its schedule as set by Lapse.
+type SM2Scheduler struct {
+	Lapse Lapse
+}
+
+func NewSM2Scheduler(lapse Lapse) *SM2Scheduler {
+	return &SM2Scheduler{lapse}
+}
+
+func (s *SM2Scheduler) Interval(card *Card) int {
+	return scheduledInterval(card)
+}
+
+func (s *SM2Scheduler) Buckets() []int {
+	return Intervals[:]
+}
+
+func (s *SM2Scheduler) Due(card *Card, now time.Time) (bool, int) {
+	interval := s.Interval(card)
+	return dueAfter(card, interval, now), interval
+}
+
+func (s *SM2Scheduler) Review(card *Card, grade Grade, now time.Time) {
+	// quality of the response on the 0 to 5 scale used by SM-2
+	quality := 4
+	if grade == Again {
+		quality = 1
+	} else if grade == Hard {
+		quality = 3
+	} else if grade == Easy {
+		quality = 5
+	}
+
+	ease := card.Ease
+	if ease == 0 {
+		ease = sm2InitialEase
+	}
+
+	if quality >= 3 {
+		interval := s.Interval(card)
+		if card.CorrectCount == 0 {
+			card.ScheduledDays = 1
+			if grade == Easy {
+				card.ScheduledDays = sm2EasyFirstInterval
+			}
+		} else if grade == Hard {
+			card.ScheduledDays = int(math.Round(float64(interval) * sm2HardInterval))
+			// a hard card still moves up the schedule
+			if card.ScheduledDays <= interval {
+				card.ScheduledDays = interval + 1
+			}
+		} else {
+			days := float64(interval) * ease
+			if interval == 1 {
+				days = 6
+			}
+			if grade == Easy {
+				days *= sm2EasyBonus
+			}
+			card.ScheduledDays = int(math.Round(days))
+		}
+		if card.ScheduledDays < 1 {
+			card.ScheduledDays = 1
+		}
+		card.CorrectCount += 1
+	} else {
+		s.lapse(card, ease)
+	}
+
+	if grade == Again {
+		// the SM-2 formula would lower the ease factor by 0.54, which makes cards that lapse come back too often
+		ease -= 0.2
+	} else {
+		q := float64(5 - quality)
+		ease += 0.1 - q*(0.08+q*0.02)
+	}
+	if ease < sm2MinimumEase {
+		ease = sm2MinimumEase
+	}
+	card.Ease = ease
+	card.LastReviewTime = now
+}
+
+func (s *SM2Scheduler) lapse(card *Card, ease float64) {
+	interval := float64(s.Interval(card))
+	if s.Lapse.Steps > 0 {
+		// each step back undoes one growth of the interval by the ease factor
+		interval = interval / math.Pow(ease, float64(s.Lapse.Steps))
+		card.CorrectCount -= s.Lapse.Steps
+	} else if s.Lapse.Fraction > 0 {
+		interval = interval * s.Lapse.Fraction
+	} else {
+		interval = 0
+	}
+	card.ScheduledDays = int(math.Round(interval))
+	if card.ScheduledDays < 1 || card.CorrectCount < 1 {
+		card.CorrectCount = 0
+		card.ScheduledDays = 0
+	}
+}
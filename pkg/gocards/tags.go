@@ -0,0 +1,63 @@
+package gocards
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// a tag is a # followed by a letter and then letters, numbers, "_", "-" or "/"
+var tagRegexp = regexp.MustCompile(`^#\pL[\pL\pN_/-]*$`)
+
+// parseTags returns the tags in the tags side of a card line without their #s.
+// The tags side is the third side of a card line, after the front and back.
+// A third side was not allowed before tags, so card lines without tags are read the same as before.
+// An error is returned if the side has anything that is not a tag.
+func parseTags(side string) ([]string, error) {
+	tags := []string{}
+	for _, field := range strings.Fields(side) {
+		if !tagRegexp.MatchString(field) {
+			return nil, errors.New("Invalid tag")
+		}
+		tags = append(tags, field[1:])
+	}
+	return tags, nil
+}
+
+func (card *Card) HasTag(tag string) bool {
+	for _, t := range card.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func GetTaggedCards(cards []*Card, tag string) []*Card {
+	foundCards := []*Card{}
+	for _, card := range cards {
+		if card.HasTag(tag) {
+			foundCards = append(foundCards, card)
+		}
+	}
+	return foundCards
+}
+
+// Tags returns the tags used by cards in the card sets in sorted order.
+func Tags(cardSets []*CardSet) []string {
+	found := map[string]bool{}
+	tags := []string{}
+	for _, cardSet := range cardSets {
+		for _, card := range cardSet.Cards {
+			for _, tag := range card.Tags {
+				if !found[tag] {
+					found[tag] = true
+					tags = append(tags, tag)
+				}
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}